@@ -0,0 +1,150 @@
+package screenshot
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/mafredri/cdp"
+	"github.com/mafredri/cdp/protocol/accessibility"
+	"github.com/mafredri/cdp/protocol/dom"
+	"github.com/mafredri/cdp/protocol/domsnapshot"
+)
+
+// AXNode - node of computed accessibility tree, Result.Accessibility holds JSON encoded list of them
+// in document order, ignored nodes are left out and their children are attached to nearest kept ancestor.
+type AXNode struct {
+	ID       string  `json:"id"`
+	ParentID string  `json:"parentId,omitempty"`
+	Role     string  `json:"role"`
+	Name     string  `json:"name,omitempty"`
+	Value    string  `json:"value,omitempty"`
+	Bounds   *AXRect `json:"bounds,omitempty"`
+}
+
+// AXRect - border box of node in CSS pixels relative to captured page, same coordinates as image when page is not clipped.
+type AXRect struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// accessibilityTree - returns JSON encoded accessibility tree of page, DOM domain must be enabled.
+func accessibilityTree(ctx context.Context, client *cdp.Client) ([]byte, error) {
+	tree, err := client.Accessibility.GetFullAXTree(ctx, accessibility.NewGetFullAXTreeArgs())
+	if err != nil {
+		return nil, err
+	}
+
+	bounds, err := nodeBounds(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	parents := make(map[accessibility.AXNodeID]*accessibility.AXNodeID, len(tree.Nodes))
+	ignored := make(map[accessibility.AXNodeID]bool, len(tree.Nodes))
+
+	for _, node := range tree.Nodes {
+		parents[node.NodeID] = node.ParentID
+		ignored[node.NodeID] = node.Ignored
+	}
+
+	// keptParent - returns nearest ancestor that is not ignored
+	keptParent := func(id *accessibility.AXNodeID) string {
+		for id != nil && ignored[*id] {
+			id = parents[*id]
+		}
+
+		if id == nil {
+			return ""
+		}
+
+		return string(*id)
+	}
+
+	nodes := make([]AXNode, 0, len(tree.Nodes))
+
+	for _, node := range tree.Nodes {
+		if node.Ignored {
+			continue
+		}
+
+		out := AXNode{
+			ID:       string(node.NodeID),
+			ParentID: keptParent(node.ParentID),
+			Role:     axValueString(node.Role),
+			Name:     axValueString(node.Name),
+			Value:    axValueString(node.Value),
+		}
+
+		if node.BackendDOMNodeID != nil {
+			out.Bounds = bounds[*node.BackendDOMNodeID]
+		}
+
+		nodes = append(nodes, out)
+	}
+
+	return json.Marshal(nodes)
+}
+
+// nodeBounds - returns border boxes of main document nodes with layout from single DOM snapshot,
+// keyed by backend node ID. Node split into several layout objects gets union of their boxes.
+func nodeBounds(ctx context.Context, client *cdp.Client) (map[dom.BackendNodeID]*AXRect, error) {
+	snapshot, err := client.DOMSnapshot.CaptureSnapshot(ctx, domsnapshot.NewCaptureSnapshotArgs([]string{}).
+		SetIncludeDOMRects(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := make(map[dom.BackendNodeID]*AXRect)
+
+	// other documents belong to frames and have bounds relative to them
+	if len(snapshot.Documents) == 0 {
+		return bounds, nil
+	}
+
+	doc := snapshot.Documents[0]
+
+	for i, node := range doc.Layout.NodeIndex {
+		if i >= len(doc.Layout.Bounds) || node < 0 || node >= len(doc.Nodes.BackendNodeID) {
+			continue
+		}
+
+		// rectangle is x, y, width, height
+		b := doc.Layout.Bounds[i]
+		if len(b) < 4 {
+			continue
+		}
+
+		id := doc.Nodes.BackendNodeID[node]
+
+		r, ok := bounds[id]
+		if !ok {
+			bounds[id] = &AXRect{X: b[0], Y: b[1], Width: b[2], Height: b[3]}
+
+			continue
+		}
+
+		minX, minY := min(r.X, b[0]), min(r.Y, b[1])
+		maxX, maxY := max(r.X+r.Width, b[0]+b[2]), max(r.Y+r.Height, b[1]+b[3])
+
+		*r = AXRect{X: minX, Y: minY, Width: maxX - minX, Height: maxY - minY}
+	}
+
+	return bounds, nil
+}
+
+// axValueString - returns accessibility value as text, strings are unquoted and other JSON values kept as is.
+func axValueString(v *accessibility.AXValue) string {
+	if v == nil || len(v.Value) == 0 {
+		return ""
+	}
+
+	var s string
+	if err := json.Unmarshal(v.Value, &s); err == nil {
+		return s
+	}
+
+	return string(v.Value)
+}
@@ -0,0 +1,348 @@
+package screenshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mafredri/cdp"
+	"github.com/mafredri/cdp/protocol/dom"
+	"github.com/mafredri/cdp/protocol/input"
+	"github.com/mafredri/cdp/protocol/runtime"
+)
+
+// ActionKind - kind of interaction step.
+type ActionKind string
+
+// Interaction steps, fields of Action used by each are listed next to it.
+const (
+	ActionClick  ActionKind = "click"  // Selector
+	ActionType   ActionKind = "type"   // Text, Selector is focused first when set
+	ActionPress  ActionKind = "press"  // Key
+	ActionHover  ActionKind = "hover"  // Selector
+	ActionSelect ActionKind = "select" // Selector, Text is option value or label
+	ActionWait   ActionKind = "wait"   // Duration, or Selector with Duration as timeout
+	ActionScroll ActionKind = "scroll" // Selector
+)
+
+const (
+	// actionWaitTimeout - how long ActionWait waits for selector when Duration is not set.
+	actionWaitTimeout = 10 * time.Second
+
+	// actionPollInterval - how often ActionWait checks for selector.
+	actionPollInterval = 100 * time.Millisecond
+)
+
+// Action - interaction step executed after page load and before capture.
+type Action struct {
+	Kind ActionKind
+
+	// Selector - CSS selector of element, first match is used.
+	Selector string
+
+	Text string
+
+	// Key - key name for ActionPress: Enter, Tab, Escape, Backspace, Delete, Space, ArrowUp, ArrowDown,
+	// ArrowLeft, ArrowRight, Home, End, PageUp, PageDown or single character.
+	Key string
+
+	Duration time.Duration
+}
+
+// ActionError - failure of interaction step.
+type ActionError struct {
+	Index  int
+	Action Action
+	Err    error
+}
+
+// Error - implements error interface.
+func (e *ActionError) Error() string {
+	if e.Action.Selector != "" {
+		return fmt.Sprintf("action %d (%s %q): %s", e.Index, e.Action.Kind, e.Action.Selector, e.Err.Error())
+	}
+
+	return fmt.Sprintf("action %d (%s): %s", e.Index, e.Action.Kind, e.Err.Error())
+}
+
+// Unwrap - returns underlying error.
+func (e *ActionError) Unwrap() error {
+	return e.Err
+}
+
+// errElementNotFound - selector matched no element.
+var errElementNotFound = errors.New("element not found")
+
+// key - DOM key description used to dispatch key events.
+type key struct {
+	key     string
+	code    string
+	keyCode int
+	text    string
+}
+
+// keys - named keys supported by ActionPress, DOM key value differs from name only for Space.
+var keys = map[string]key{
+	"Enter":      {"Enter", "Enter", 13, "\r"},
+	"Tab":        {"Tab", "Tab", 9, ""},
+	"Escape":     {"Escape", "Escape", 27, ""},
+	"Backspace":  {"Backspace", "Backspace", 8, ""},
+	"Delete":     {"Delete", "Delete", 46, ""},
+	"Space":      {" ", "Space", 32, " "},
+	"ArrowUp":    {"ArrowUp", "ArrowUp", 38, ""},
+	"ArrowDown":  {"ArrowDown", "ArrowDown", 40, ""},
+	"ArrowLeft":  {"ArrowLeft", "ArrowLeft", 37, ""},
+	"ArrowRight": {"ArrowRight", "ArrowRight", 39, ""},
+	"Home":       {"Home", "Home", 36, ""},
+	"End":        {"End", "End", 35, ""},
+	"PageUp":     {"PageUp", "PageUp", 33, ""},
+	"PageDown":   {"PageDown", "PageDown", 34, ""},
+}
+
+// selectOptionFunction - selects option of <select> by value or label and notifies page like user change would.
+const selectOptionFunction = `function(value) {
+	if (!(this instanceof HTMLSelectElement)) {
+		throw new Error('element is not <select>');
+	}
+	const option = Array.from(this.options).find(o => o.value === value || o.label === value);
+	if (!option) {
+		throw new Error('option ' + JSON.stringify(value) + ' not found');
+	}
+	this.value = option.value;
+	this.dispatchEvent(new Event('input', { bubbles: true }));
+	this.dispatchEvent(new Event('change', { bubbles: true }));
+}`
+
+// runActions - executes Config.Actions in order, stops at first failed step.
+func (c *Config) runActions(ctx context.Context, client *cdp.Client, log *slog.Logger) error {
+	for i, action := range c.Actions {
+		start := time.Now()
+
+		err := runAction(ctx, client, action)
+		if err != nil {
+			return &ActionError{Index: i, Action: action, Err: err}
+		}
+
+		log.Debug("action executed",
+			slog.Int("index", i),
+			slog.String("kind", string(action.Kind)),
+			slog.Duration("duration", time.Since(start)),
+		)
+	}
+
+	// let page render state caused by last action
+	return c.settleLayout(ctx, client)
+}
+
+// runAction - executes single interaction step.
+func runAction(ctx context.Context, client *cdp.Client, action Action) error {
+	switch action.Kind {
+	case ActionClick, ActionHover:
+		node, err := querySelector(ctx, client, action.Selector)
+		if err != nil {
+			return err
+		}
+
+		x, y, err := nodeCenter(ctx, client, node)
+		if err != nil {
+			return err
+		}
+
+		err = client.Input.DispatchMouseEvent(ctx, input.NewDispatchMouseEventArgs("mouseMoved", x, y))
+		if err != nil || action.Kind == ActionHover {
+			return err
+		}
+
+		for _, typ := range []string{"mousePressed", "mouseReleased"} {
+			err = client.Input.DispatchMouseEvent(ctx, input.NewDispatchMouseEventArgs(typ, x, y).
+				SetButton(input.MouseButtonLeft).
+				SetClickCount(1),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case ActionType:
+		if action.Selector != "" {
+			node, err := querySelector(ctx, client, action.Selector)
+			if err != nil {
+				return err
+			}
+
+			err = client.DOM.Focus(ctx, dom.NewFocusArgs().SetNodeID(node))
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, r := range action.Text {
+			err := pressKey(ctx, client, key{key: string(r), text: string(r)})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case ActionPress:
+		k, ok := keys[action.Key]
+		if !ok {
+			if len([]rune(action.Key)) != 1 {
+				return fmt.Errorf("unknown key %q", action.Key)
+			}
+
+			k = key{key: action.Key, text: action.Key}
+		}
+
+		return pressKey(ctx, client, k)
+	case ActionSelect:
+		node, err := querySelector(ctx, client, action.Selector)
+		if err != nil {
+			return err
+		}
+
+		obj, err := client.DOM.ResolveNode(ctx, dom.NewResolveNodeArgs().SetNodeID(node))
+		if err != nil {
+			return err
+		}
+
+		if obj.Object.ObjectID == nil {
+			return errElementNotFound
+		}
+
+		value, err := json.Marshal(action.Text)
+		if err != nil {
+			return err
+		}
+
+		reply, err := client.Runtime.CallFunctionOn(ctx, runtime.NewCallFunctionOnArgs(selectOptionFunction).
+			SetObjectID(*obj.Object.ObjectID).
+			SetArguments([]runtime.CallArgument{{Value: value}}),
+		)
+		if err != nil {
+			return err
+		}
+
+		if reply.ExceptionDetails != nil {
+			return reply.ExceptionDetails
+		}
+
+		return nil
+	case ActionWait:
+		if action.Selector == "" {
+			select {
+			case <-time.After(action.Duration):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		timeout := action.Duration
+		if timeout <= 0 {
+			timeout = actionWaitTimeout
+		}
+
+		deadline := time.Now().Add(timeout)
+
+		for {
+			_, err := querySelector(ctx, client, action.Selector)
+			if !errors.Is(err, errElementNotFound) {
+				return err
+			}
+
+			if time.Now().After(deadline) {
+				return fmt.Errorf("element did not appear within %s", timeout)
+			}
+
+			select {
+			case <-time.After(actionPollInterval):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	case ActionScroll:
+		node, err := querySelector(ctx, client, action.Selector)
+		if err != nil {
+			return err
+		}
+
+		return client.DOM.ScrollIntoViewIfNeeded(ctx, dom.NewScrollIntoViewIfNeededArgs().SetNodeID(node))
+	}
+
+	return fmt.Errorf("unknown action %q", action.Kind)
+}
+
+// querySelector - returns node of first element matching selector in current document.
+func querySelector(ctx context.Context, client *cdp.Client, selector string) (dom.NodeID, error) {
+	if selector == "" {
+		return 0, errors.New("empty selector")
+	}
+
+	// document is requested every time, node ids of previous one are invalid after page changes
+	doc, err := client.DOM.GetDocument(ctx, dom.NewGetDocumentArgs().SetDepth(0))
+	if err != nil {
+		return 0, err
+	}
+
+	reply, err := client.DOM.QuerySelector(ctx, dom.NewQuerySelectorArgs(doc.Root.NodeID, selector))
+	if err != nil {
+		return 0, err
+	}
+
+	if reply.NodeID == 0 {
+		return 0, errElementNotFound
+	}
+
+	return reply.NodeID, nil
+}
+
+// nodeCenter - scrolls element into view and returns its center in viewport coordinates.
+func nodeCenter(ctx context.Context, client *cdp.Client, node dom.NodeID) (float64, float64, error) {
+	err := client.DOM.ScrollIntoViewIfNeeded(ctx, dom.NewScrollIntoViewIfNeededArgs().SetNodeID(node))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	quads, err := client.DOM.GetContentQuads(ctx, dom.NewGetContentQuadsArgs().SetNodeID(node))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, q := range quads.Quads {
+		if len(q) < 8 {
+			continue
+		}
+
+		// quad is four x, y points, its center is average of them
+		return (q[0] + q[2] + q[4] + q[6]) / 4, (q[1] + q[3] + q[5] + q[7]) / 4, nil
+	}
+
+	return 0, 0, errors.New("element is not visible")
+}
+
+// pressKey - dispatches key down and key up events, key with text also produces character input.
+func pressKey(ctx context.Context, client *cdp.Client, k key) error {
+	down := input.NewDispatchKeyEventArgs("rawKeyDown").SetKey(k.key)
+	up := input.NewDispatchKeyEventArgs("keyUp").SetKey(k.key)
+
+	if k.text != "" {
+		down = input.NewDispatchKeyEventArgs("keyDown").SetKey(k.key).SetText(k.text)
+	}
+
+	if k.code != "" {
+		down.SetCode(k.code).SetWindowsVirtualKeyCode(k.keyCode)
+		up.SetCode(k.code).SetWindowsVirtualKeyCode(k.keyCode)
+	}
+
+	err := client.Input.DispatchKeyEvent(ctx, down)
+	if err != nil {
+		return err
+	}
+
+	return client.Input.DispatchKeyEvent(ctx, up)
+}
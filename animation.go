@@ -0,0 +1,242 @@
+package screenshot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"time"
+)
+
+// lastFrameDelay - how long last frame of animation is shown before it loops.
+const lastFrameDelay = time.Second
+
+// frameCanvas - decodes frames one by one onto opaque canvas of first frame size, canvas is reused between frames.
+// Screencast frames change size when viewport is resized for full page capture, canvas keeps them uniform.
+type frameCanvas struct {
+	frames []Frame
+	canvas *image.RGBA
+}
+
+// newFrameCanvas - returns canvas sized by first frame.
+func newFrameCanvas(frames []Frame) (*frameCanvas, error) {
+	if len(frames) == 0 {
+		return nil, errors.New("no frames recorded")
+	}
+
+	cfg, err := png.DecodeConfig(bytes.NewReader(frames[0].Data))
+	if err != nil {
+		return nil, err
+	}
+
+	return &frameCanvas{frames: frames, canvas: image.NewRGBA(image.Rect(0, 0, cfg.Width, cfg.Height))}, nil
+}
+
+// draw - decodes frame i onto canvas and returns how long it is shown.
+func (f *frameCanvas) draw(i int) (time.Duration, error) {
+	img, err := png.Decode(bytes.NewReader(f.frames[i].Data))
+	if err != nil {
+		return 0, err
+	}
+
+	bounds := f.canvas.Bounds()
+	draw.Draw(f.canvas, bounds, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(f.canvas, bounds, img, img.Bounds().Min, draw.Over)
+
+	if i+1 == len(f.frames) {
+		return lastFrameDelay, nil
+	}
+
+	return max(f.frames[i+1].Timestamp.Sub(f.frames[i].Timestamp), 10*time.Millisecond), nil
+}
+
+// encodeGIF - encodes frames as looping animated GIF.
+// Every frame is encoded with image/gif as single image GIF, its image block is reused as animation frame.
+func encodeGIF(frames []Frame) ([]byte, error) {
+	fc, err := newFrameCanvas(frames)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := fc.canvas.Bounds()
+	paletted := image.NewPaletted(bounds, palette.Plan9)
+
+	var out bytes.Buffer
+
+	// header, logical screen descriptor without global color table and NETSCAPE2.0 extension looping forever
+	out.WriteString("GIF89a")
+	_ = binary.Write(&out, binary.LittleEndian, [2]uint16{uint16(bounds.Dx()), uint16(bounds.Dy())})
+	out.Write([]byte{0, 0, 0})
+	out.Write([]byte{0x21, 0xff, 0x0b})
+	out.WriteString("NETSCAPE2.0")
+	out.Write([]byte{0x03, 0x01, 0x00, 0x00, 0x00})
+
+	for i := range frames {
+		delay, err := fc.draw(i)
+		if err != nil {
+			return nil, err
+		}
+
+		draw.FloydSteinberg.Draw(paletted, bounds, fc.canvas, image.Point{})
+
+		var buf bytes.Buffer
+
+		err = gif.EncodeAll(&buf, &gif.GIF{
+			Image: []*image.Paletted{paletted},
+			Delay: []int{int(delay / (10 * time.Millisecond))}, // GIF delay unit is 1/100s
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// single image GIF has no global color table nor extensions before image, so its header is
+		// 6 bytes of signature and 7 bytes of logical screen descriptor, trailer is 1 byte
+		const headerLen = 13
+
+		b := buf.Bytes()
+		if len(b) < headerLen+1 {
+			return nil, errors.New("invalid GIF data")
+		}
+
+		out.Write(b[headerLen : len(b)-1])
+	}
+
+	out.WriteByte(0x3b) // trailer
+
+	return out.Bytes(), nil
+}
+
+// encodeAPNG - encodes frames as looping animated PNG.
+// Every frame is encoded with image/png, its IDAT chunks are reused as frame data.
+func encodeAPNG(frames []Frame) ([]byte, error) {
+	fc, err := newFrameCanvas(frames)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		out bytes.Buffer
+		seq uint32
+	)
+
+	out.WriteString("\x89PNG\r\n\x1a\n")
+
+	for i := range frames {
+		delay, err := fc.draw(i)
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+
+		err = png.Encode(&buf, fc.canvas)
+		if err != nil {
+			return nil, err
+		}
+
+		chunks, err := readPNGChunks(buf.Bytes())
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 {
+			for _, chunk := range chunks {
+				if chunk.typ == "IHDR" {
+					writePNGChunk(&out, "IHDR", chunk.data)
+				}
+			}
+
+			actl := make([]byte, 8)
+			binary.BigEndian.PutUint32(actl[0:], uint32(len(frames)))
+			binary.BigEndian.PutUint32(actl[4:], 0) // loop forever
+			writePNGChunk(&out, "acTL", actl)
+		}
+
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:], seq)
+		binary.BigEndian.PutUint32(fctl[4:], uint32(fc.canvas.Bounds().Dx()))
+		binary.BigEndian.PutUint32(fctl[8:], uint32(fc.canvas.Bounds().Dy()))
+		// x and y offsets stay zero, delay is expressed in milliseconds
+		binary.BigEndian.PutUint16(fctl[20:], uint16(min(delay.Milliseconds(), 65535)))
+		binary.BigEndian.PutUint16(fctl[22:], 1000)
+		// dispose and blend operations stay zero: none and source
+		writePNGChunk(&out, "fcTL", fctl)
+		seq++
+
+		for _, chunk := range chunks {
+			if chunk.typ != "IDAT" {
+				continue
+			}
+
+			if i == 0 {
+				writePNGChunk(&out, "IDAT", chunk.data)
+
+				continue
+			}
+
+			fdat := make([]byte, 4, 4+len(chunk.data))
+			binary.BigEndian.PutUint32(fdat, seq)
+			writePNGChunk(&out, "fdAT", append(fdat, chunk.data...))
+			seq++
+		}
+	}
+
+	writePNGChunk(&out, "IEND", nil)
+
+	return out.Bytes(), nil
+}
+
+// pngChunk - raw PNG chunk without length and CRC.
+type pngChunk struct {
+	typ  string
+	data []byte
+}
+
+// readPNGChunks - splits PNG file into chunks.
+func readPNGChunks(b []byte) ([]pngChunk, error) {
+	const signatureLen = 8
+
+	if len(b) < signatureLen {
+		return nil, errors.New("invalid PNG data")
+	}
+
+	var chunks []pngChunk
+
+	for b = b[signatureLen:]; len(b) > 0; {
+		if len(b) < 12 {
+			return nil, errors.New("truncated PNG chunk")
+		}
+
+		n := int(binary.BigEndian.Uint32(b))
+		if len(b) < 12+n {
+			return nil, errors.New("truncated PNG chunk")
+		}
+
+		chunks = append(chunks, pngChunk{typ: string(b[4:8]), data: b[8 : 8+n]})
+		b = b[12+n:]
+	}
+
+	return chunks, nil
+}
+
+// writePNGChunk - writes PNG chunk with length and CRC.
+func writePNGChunk(buf *bytes.Buffer, typ string, data []byte) {
+	var header [8]byte
+
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], typ)
+	buf.Write(header[:])
+	buf.Write(data)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+
+	_ = binary.Write(buf, binary.BigEndian, crc.Sum32())
+}
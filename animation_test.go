@@ -0,0 +1,145 @@
+package screenshot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testFrames - returns solid colour PNG frames 100ms apart, second frame is larger like after full page resize.
+func testFrames(t *testing.T) []Frame {
+	t.Helper()
+
+	start := time.Now()
+	colors := []color.RGBA{{R: 0xff, A: 0xff}, {G: 0xff, A: 0xff}, {B: 0xff, A: 0xff}}
+	sizes := []image.Point{{40, 30}, {40, 60}, {40, 30}}
+
+	var frames []Frame
+
+	for i, c := range colors {
+		img := image.NewRGBA(image.Rectangle{Max: sizes[i]})
+		for j := 0; j < len(img.Pix); j += 4 {
+			img.Pix[j], img.Pix[j+1], img.Pix[j+2], img.Pix[j+3] = c.R, c.G, c.B, c.A
+		}
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatal(err)
+		}
+
+		frames = append(frames, Frame{Data: buf.Bytes(), Timestamp: start.Add(time.Duration(i) * 100 * time.Millisecond)})
+	}
+
+	return frames
+}
+
+func TestEncodeAPNG(t *testing.T) {
+	frames := testFrames(t)
+
+	b, err := encodeAPNG(frames)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// decoders without APNG support show default image, which is first frame
+	img, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if img.Bounds().Dx() != 40 || img.Bounds().Dy() != 30 {
+		t.Errorf("image size %dx%d, want 40x30", img.Bounds().Dx(), img.Bounds().Dy())
+	}
+
+	if got := color.RGBAModel.Convert(img.At(20, 15)).(color.RGBA); got != (color.RGBA{R: 0xff, A: 0xff}) {
+		t.Errorf("pixel is %v, want red of first frame", got)
+	}
+
+	chunks, err := readPNGChunks(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		actl   uint32
+		fctl   int
+		delays []uint16
+	)
+
+	for _, chunk := range chunks {
+		switch chunk.typ {
+		case "acTL":
+			actl = binary.BigEndian.Uint32(chunk.data)
+		case "fcTL":
+			fctl++
+			delays = append(delays, binary.BigEndian.Uint16(chunk.data[20:]))
+		}
+	}
+
+	if int(actl) != len(frames) || fctl != len(frames) {
+		t.Errorf("acTL frame count %d, %d fcTL chunks, want %d", actl, fctl, len(frames))
+	}
+
+	if want := []uint16{100, 100, uint16(lastFrameDelay.Milliseconds())}; !slices.Equal(delays, want) {
+		t.Errorf("delays %v ms, want %v", delays, want)
+	}
+}
+
+func TestEncodeGIF(t *testing.T) {
+	frames := testFrames(t)
+
+	b, err := encodeGIF(frames)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	anim, err := gif.DecodeAll(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(anim.Image) != len(frames) {
+		t.Fatalf("%d frames, want %d", len(anim.Image), len(frames))
+	}
+
+	if anim.LoopCount != 0 {
+		t.Errorf("loop count %d, want 0 (forever)", anim.LoopCount)
+	}
+
+	if anim.Config.Width != 40 || anim.Config.Height != 30 {
+		t.Errorf("image size %dx%d, want 40x30", anim.Config.Width, anim.Config.Height)
+	}
+
+	want := []uint16{10, 10, uint16(lastFrameDelay / (10 * time.Millisecond))}
+	got := make([]uint16, len(anim.Delay))
+
+	for i, d := range anim.Delay {
+		got[i] = uint16(d)
+	}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("delays %v, want %v", got, want)
+	}
+
+	for i, img := range anim.Image {
+		if img.Bounds() != image.Rect(0, 0, 40, 30) {
+			t.Errorf("frame %d bounds %v, want canvas of first frame", i, img.Bounds())
+		}
+	}
+}
+
+func TestEncodeNoFrames(t *testing.T) {
+	if _, err := encodeAPNG(nil); err == nil {
+		t.Error("encodeAPNG succeeded without frames")
+	}
+
+	if _, err := encodeGIF(nil); err == nil {
+		t.Error("encodeGIF succeeded without frames")
+	}
+}
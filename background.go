@@ -0,0 +1,32 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mafredri/cdp"
+	"github.com/mafredri/cdp/protocol/dom"
+	"github.com/mafredri/cdp/protocol/emulation"
+)
+
+// omitBackground - makes default page backdrop transparent, so pages without own background are captured with alpha channel.
+// Returned function restores default background, only formats with alpha channel are accepted.
+func omitBackground(ctx context.Context, client *cdp.Client, format string) (func(context.Context) error, error) {
+	if format != "png" && format != "webp" {
+		return nil, fmt.Errorf("transparent background is not supported for %s format", format)
+	}
+
+	transparent := 0.0
+
+	err := client.Emulation.SetDefaultBackgroundColorOverride(ctx,
+		emulation.NewSetDefaultBackgroundColorOverrideArgs().SetColor(dom.RGBA{A: &transparent}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context) error {
+		// override without colour restores default
+		return client.Emulation.SetDefaultBackgroundColorOverride(ctx, emulation.NewSetDefaultBackgroundColorOverrideArgs())
+	}, nil
+}
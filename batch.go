@@ -0,0 +1,216 @@
+package screenshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// errSharedProfile - browsers of concurrent workers would use the same profile directory, Chrome allows only one
+	// browser per profile and launch removes DevToolsActivePort file other worker is waiting for.
+	errSharedProfile = errors.New("concurrent batch workers can not share profile directory, set RandomProfileDir")
+
+	// errSharedPort - browsers of concurrent workers would listen on the same DevTools port.
+	errSharedPort = errors.New("concurrent batch workers can not share DevTools port, set Port to 0 or use Pipe")
+)
+
+// BatchResult - outcome of single Batch job, Index is position of job in input.
+type BatchResult struct {
+	Index  int
+	Result *Result
+	Err    error
+}
+
+// Batch - captures configs received from channel using up to workers browsers at once.
+// Results are streamed in completion order, output channel is closed once input channel
+// is closed and drained or context is done, so consumer must read until it is closed.
+//
+// Each worker keeps its browser running between jobs and opens every job in a separate browser context,
+// browser is relaunched only when launch options (binary, flags, language, user agent, transport, limits) change.
+// Browser output writer of the job that launched browser is used for all jobs sharing it.
+// With more than one worker jobs must use random profile directory and either random DevTools port or pipe,
+// jobs that do not are failed without launching browser.
+func Batch(ctx context.Context, workers int, configs <-chan Config) <-chan BatchResult {
+	if workers < 1 {
+		workers = 1
+	}
+
+	type job struct {
+		index  int
+		config Config
+	}
+
+	jobs := make(chan job)
+	out := make(chan BatchResult)
+
+	go func() {
+		defer close(jobs)
+
+		for index := 0; ; index++ {
+			select {
+			case <-ctx.Done():
+				return
+			case config, ok := <-configs:
+				if !ok {
+					return
+				}
+
+				select {
+				case jobs <- job{index: index, config: config}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	done := make(chan struct{})
+
+	for range workers {
+		go func() {
+			defer func() { done <- struct{}{} }()
+
+			w := &batchWorker{concurrent: workers > 1}
+			defer w.close()
+
+			for j := range jobs {
+				r, err := w.run(ctx, &j.config)
+
+				select {
+				case out <- BatchResult{Index: j.index, Result: r, Err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		for range workers {
+			<-done
+		}
+
+		close(out)
+	}()
+
+	return out
+}
+
+// BatchSlice - like Batch, takes jobs from slice.
+func BatchSlice(ctx context.Context, workers int, configs []Config) <-chan BatchResult {
+	in := make(chan Config)
+
+	go func() {
+		defer close(in)
+
+		for _, config := range configs {
+			select {
+			case in <- config:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return Batch(ctx, workers, in)
+}
+
+// launchOptions - options that are applied when browser starts, jobs with equal options share browser.
+type launchOptions struct {
+	cmd, host, lang, userAgent string
+	flags                      string
+	port                       int
+	randomProfileDir, pipe     bool
+	memory                     int64
+	cpu                        time.Duration
+	files                      uint64
+}
+
+func (c *Config) launchOptions() launchOptions {
+	return launchOptions{
+		cmd:              c.CMD,
+		host:             c.Host,
+		lang:             c.AcceptLanguage,
+		userAgent:        c.UserAgent,
+		flags:            fmt.Sprintf("%q", c.Flags),
+		port:             c.Port,
+		randomProfileDir: c.RandomProfileDir,
+		pipe:             c.Pipe,
+		memory:           c.MemoryLimit,
+		cpu:              c.CPUTimeLimit,
+		files:            c.OpenFilesLimit,
+	}
+}
+
+// batchWorker - runs Batch jobs one by one, reusing browser between them.
+type batchWorker struct {
+	b      *browser
+	config *Config // config browser was launched with
+	opts   launchOptions
+
+	concurrent bool // other workers launch browsers at the same time
+}
+
+func (w *batchWorker) run(ctx context.Context, cfg *Config) (*Result, error) {
+	c := cfg.clone()
+	c.normalize()
+
+	if w.concurrent && !c.RandomProfileDir {
+		return nil, fmt.Errorf("failed to capture URL=%q: %w", c.URL, errSharedProfile)
+	}
+
+	if w.concurrent && !c.Pipe && c.Port != 0 {
+		return nil, fmt.Errorf("failed to capture URL=%q: %w", c.URL, errSharedPort)
+	}
+
+	opts := c.launchOptions()
+
+	if w.b != nil {
+		select {
+		case <-w.b.exited:
+			w.close()
+		default:
+			if opts != w.opts {
+				w.close()
+			}
+		}
+	}
+
+	if w.b == nil {
+		// proxy is applied per browser context, so jobs with different proxies share browser
+		lc := c.clone()
+		lc.Proxy = Proxy{}
+
+		// browser outlives single job, so it is bound to batch context instead of job deadline
+		b, err := lc.launch(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		w.b, w.config, w.opts = b, lc, opts
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.ContextDeadline)
+	defer cancel()
+
+	t, err := w.b.newPage(ctx, true, c.Proxy)
+	if err != nil {
+		return nil, w.b.limitError(ctx, w.b.outputError(fmt.Errorf("failed to open page for URL=%q: %w", c.URL, err)), "")
+	}
+	defer t.close()
+
+	r, err := c.capture(ctx, t)
+
+	return r, w.b.limitError(ctx, err, t.id)
+}
+
+func (w *batchWorker) close() {
+	if w.b == nil {
+		return
+	}
+
+	w.b.close(w.config)
+	w.b, w.config = nil, nil
+}
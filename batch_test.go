@@ -0,0 +1,73 @@
+package screenshot
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBatchConcurrentWorkers(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing-browser")
+	// shared profile directory is created in temporary directory
+	t.Setenv("TMPDIR", t.TempDir())
+
+	tests := []struct {
+		name    string
+		workers int
+		config  func(c *Config)
+		wantErr error
+	}{
+		{name: "random profile", workers: 3},
+		{name: "shared profile", workers: 3, config: func(c *Config) { c.RandomProfileDir = false }, wantErr: errSharedProfile},
+		{name: "fixed port", workers: 3, config: func(c *Config) { c.Port = 9222 }, wantErr: errSharedPort},
+		{name: "fixed port over pipe", workers: 3, config: func(c *Config) { c.Port, c.Pipe = 9222, true }},
+		{name: "single worker with shared profile", workers: 1, config: func(c *Config) { c.RandomProfileDir = false }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			configs := make([]Config, 6)
+			for i := range configs {
+				configs[i] = DefaultConfig()
+				// launch fails right after checks, so no browser is needed
+				configs[i].CMD = missing
+				if tt.config != nil {
+					tt.config(&configs[i])
+				}
+			}
+
+			seen := make(map[int]bool)
+
+			for r := range BatchSlice(ctx, tt.workers, configs) {
+				if seen[r.Index] {
+					t.Errorf("job %d reported twice", r.Index)
+				}
+
+				seen[r.Index] = true
+
+				if r.Err == nil {
+					t.Errorf("job %d succeeded with missing browser binary", r.Index)
+
+					continue
+				}
+
+				if tt.wantErr != nil && !errors.Is(r.Err, tt.wantErr) {
+					t.Errorf("job %d: error %v, want %v", r.Index, r.Err, tt.wantErr)
+				}
+
+				if tt.wantErr == nil && (errors.Is(r.Err, errSharedProfile) || errors.Is(r.Err, errSharedPort)) {
+					t.Errorf("job %d rejected: %v", r.Index, r.Err)
+				}
+			}
+
+			if len(seen) != len(configs) {
+				t.Errorf("%d results, want %d", len(seen), len(configs))
+			}
+		})
+	}
+}
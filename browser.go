@@ -0,0 +1,477 @@
+package screenshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mafredri/cdp"
+	"github.com/mafredri/cdp/devtool"
+	"github.com/mafredri/cdp/protocol/target"
+	"github.com/mafredri/cdp/rpcc"
+)
+
+// pageCloseTimeout - upper bound for closing page target and its browser context.
+const pageCloseTimeout = 5 * time.Second
+
+// browser - running Chrome instance, reachable either over DevTools TCP port or over pipe.
+type browser struct {
+	cmd    *exec.Cmd
+	exited chan struct{}
+
+	host string
+	port int
+
+	pipe *pipeConn
+	conn *rpcc.Conn // browser target connection, nil for browser not started by launch
+
+	limits limitSet
+	cgroup *cgroup
+
+	mu      sync.Mutex
+	crashes map[string]targetCrash // renderer terminations by target ID
+
+	output  *ringBuffer
+	drained <-chan struct{}
+
+	log *slog.Logger
+}
+
+// launch - starts Chrome for config and waits until DevTools endpoint is ready.
+func (c *Config) launch(ctx context.Context) (_ *browser, err error) {
+	b := &browser{
+		exited:  make(chan struct{}),
+		host:    c.Host,
+		port:    c.Port,
+		crashes: make(map[string]targetCrash),
+		log:     c.logger(),
+	}
+
+	start := time.Now()
+
+	defer func() {
+		if err != nil {
+			b.close(c)
+		}
+	}()
+
+	if c.RandomProfileDir {
+		c.ProfileDir, err = os.MkdirTemp(os.TempDir(), "cdp")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get temporary directory for URL=%q: %w", c.URL, err)
+		}
+
+		// lets Cleanup find directory if this process dies before removing it
+		err = writeOwnerFile(c.ProfileDir, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to mark temporary directory for URL=%q: %w", c.URL, err)
+		}
+	} else {
+		c.ProfileDir = filepath.Join(os.TempDir(), "cdp")
+
+		err = os.MkdirAll(c.ProfileDir, 0666)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get temporary directory for URL=%q: %w", c.URL, err)
+		}
+	}
+
+	flags := slices.Clone(c.Flags)
+
+	if c.Pipe {
+		flags = append(flags, "--remote-debugging-pipe")
+	} else {
+		flags = append(flags, fmt.Sprintf("--remote-debugging-port=%s", strconv.Itoa(c.Port)))
+	}
+
+	flags = append(flags, fmt.Sprintf("--window-size=%d,%d", c.WindowWidth, c.WindowHeight))
+
+	if c.AcceptLanguage != "" {
+		flags = append(flags, fmt.Sprintf("--lang=%s", c.AcceptLanguage))
+	}
+
+	if c.UserAgent != "" {
+		flags = append(flags, fmt.Sprintf("--user-agent=%s", c.UserAgent))
+	}
+
+	if len(c.ProfileDir) > 0 {
+		flags = append(flags, fmt.Sprintf("--user-data-dir=%s", c.ProfileDir))
+	}
+
+	if c.Proxy.enabled() {
+		proxyFlags, err := c.Proxy.flags()
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure proxy for URL=%q: %w", c.URL, err)
+		}
+
+		flags = append(flags, proxyFlags...)
+	}
+
+	b.output = newRingBuffer(browserOutputSize)
+
+	out, drained, err := captureOutput(b.output, c.BrowserOutput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture browser output for URL=%q: %w", c.URL, err)
+	}
+	defer out.Close()
+
+	b.drained = drained
+
+	if c.MemoryLimit > 0 {
+		b.cgroup = newCgroup(c.MemoryLimit)
+	}
+
+	var extraFiles []*os.File
+
+	if c.Pipe {
+		var chromeIn, chromeOut *os.File
+
+		chromeIn, chromeOut, b.pipe, err = openPipes()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create remote debugging pipe for URL=%q: %w", c.URL, err)
+		}
+
+		// Chrome expects pipe at fd 3 for reading and fd 4 for writing, parent copies are not needed after start
+		extraFiles = []*os.File{chromeIn, chromeOut}
+
+		defer chromeIn.Close()
+		defer chromeOut.Close()
+	} else {
+		// stale file from previous run in shared profile directory would point to wrong port
+		_ = os.Remove(filepath.Join(c.ProfileDir, devToolsActivePortFile))
+	}
+
+	b.log.Debug("launching browser",
+		slog.String("cmd", c.CMD),
+		slog.Bool("pipe", c.Pipe),
+		slog.String("profile_dir", c.ProfileDir),
+	)
+
+	err = b.start(ctx, c, flags, out, extraFiles)
+	if err != nil && b.cgroup != nil {
+		// starting directly in cgroup is refused e.g. by old kernels or without write access to cgroup.procs,
+		// memory is limited by rlimit then
+		b.log.Debug("starting browser in cgroup failed, retrying without it", slog.String("error", err.Error()))
+
+		b.cgroup.remove()
+		b.cgroup = nil
+
+		err = b.start(ctx, c, flags, out, extraFiles)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to start CDP for url=%s: %w", c.URL, err)
+	}
+
+	b.limits = limitSet{memory: c.MemoryLimit > 0, cpu: c.CPUTimeLimit, files: c.OpenFilesLimit > 0}
+
+	// browser output is attached to errors from here on
+	defer func() {
+		if err != nil {
+			err = b.outputError(err)
+		}
+	}()
+
+	cmd := b.cmd
+
+	go func() {
+		defer close(b.exited)
+		_ = cmd.Wait()
+	}()
+
+	if c.RandomProfileDir {
+		// process group ID equals browser PID because of Setpgid
+		err = writeOwnerFile(c.ProfileDir, cmd.Process.Pid)
+		if err != nil {
+			return nil, fmt.Errorf("failed to mark temporary directory for URL=%q: %w", c.URL, err)
+		}
+	}
+
+	b.log.Info("browser started", slog.Int("pid", cmd.Process.Pid))
+
+	if c.Pipe {
+		b.conn, err = b.pipe.dial(ctx, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to CDP pipe for URL=%q: %w", c.URL, err)
+		}
+
+		err = b.watchCrashes(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to watch page targets for URL=%q: %w", c.URL, err)
+		}
+
+		b.log.Info("DevTools ready", slog.String("transport", "pipe"), slog.Duration("duration", time.Since(start)))
+
+		return b, nil
+	}
+
+	waitCtx := ctx
+
+	if c.ContextDeadline > 0 {
+		var cancel context.CancelFunc
+
+		waitCtx, cancel = context.WithTimeout(ctx, c.ContextDeadline)
+		defer cancel()
+	}
+
+	// port 0 lets Chrome bind the port itself, actual port is reported back via profile directory
+	port, path, err := waitDevToolsActivePort(waitCtx, c.ProfileDir, b.exited)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for DevTools endpoint for URL=%q: %w", c.URL, err)
+	}
+
+	b.port = port
+
+	b.conn, err = rpcc.DialContext(waitCtx, fmt.Sprintf("ws://%s:%d%s", b.host, b.port, path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to CDP for URL=%q: %w", c.URL, err)
+	}
+
+	err = b.watchCrashes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch page targets for URL=%q: %w", c.URL, err)
+	}
+
+	b.log.Info("DevTools ready",
+		slog.String("transport", "tcp"),
+		slog.Int("port", b.port),
+		slog.Duration("duration", time.Since(start)),
+	)
+
+	return b, nil
+}
+
+// start - starts browser in its own process group, inside cgroup when browser has one. Resource limits are set
+// by shell that replaces itself with browser, so browser keeps shell PID and process group.
+func (b *browser) start(ctx context.Context, c *Config, flags []string, out *os.File, extraFiles []*os.File) error {
+	name, args := c.CMD, flags
+
+	if script := c.rlimitScript(b.cgroup != nil); script != "" {
+		name, args = "/bin/sh", append([]string{"-c", script, c.CMD}, flags...)
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.ExtraFiles = extraFiles
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid:   true,
+		Pdeathsig: syscall.SIGKILL,
+	}
+
+	if b.cgroup != nil {
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = int(b.cgroup.fd.Fd())
+	}
+
+	b.cmd = cmd
+
+	return cmd.Start()
+}
+
+// outputError - attaches recent browser output to error.
+func (b *browser) outputError(err error) error {
+	if b.output == nil {
+		return err
+	}
+
+	// browser that exited early may still have output in flight
+	select {
+	case <-b.exited:
+		select {
+		case <-b.drained:
+		case <-time.After(100 * time.Millisecond):
+		}
+	default:
+	}
+
+	return &BrowserError{
+		Err:    err,
+		Output: b.output.String(),
+	}
+}
+
+// close - closes browser connection, kills Chrome process group and removes profile directory.
+func (b *browser) close(c *Config) {
+	start := time.Now()
+
+	if b.conn != nil {
+		_ = b.conn.Close()
+	}
+
+	if b.pipe != nil {
+		_ = b.pipe.Close()
+	}
+
+	c.KillByPGIDAndCleanup(b.cmd)
+
+	if b.cgroup != nil {
+		b.cgroup.remove()
+	}
+
+	b.log.Debug("browser cleaned up", slog.Duration("duration", time.Since(start)))
+}
+
+// tab - page target opened in browser.
+type tab struct {
+	id      string
+	conn    *rpcc.Conn
+	closeFn func()
+}
+
+// close - closes connection to page and page target itself.
+func (t *tab) close() {
+	t.closeFn()
+}
+
+// newPage - opens new page target and connects to it. Isolated page gets its own browser context,
+// so pages opened one after another in the same browser share no cookies or cache, otherwise page is opened
+// in default context and sees profile state. Isolated context is routed through proxy when it is set,
+// otherwise it inherits browser proxy settings.
+func (b *browser) newPage(ctx context.Context, isolated bool, proxy Proxy) (_ *tab, err error) {
+	if b.conn == nil {
+		return b.newDevToolsPage(ctx)
+	}
+
+	var cleanup []func(context.Context)
+
+	closeFn := func() {
+		// page is closed after capture, when capture context may already be done
+		ctx, cancel := context.WithTimeout(context.Background(), pageCloseTimeout)
+		defer cancel()
+
+		for i := len(cleanup) - 1; i >= 0; i-- {
+			cleanup[i](ctx)
+		}
+	}
+
+	defer func() {
+		if err != nil {
+			closeFn()
+		}
+	}()
+
+	bc := cdp.NewClient(b.conn)
+
+	targetArgs := target.NewCreateTargetArgs("about:blank")
+
+	if isolated {
+		contextArgs := target.NewCreateBrowserContextArgs()
+
+		if proxy.enabled() {
+			server, err := proxy.server()
+			if err != nil {
+				return nil, err
+			}
+
+			contextArgs.SetProxyServer(server)
+
+			if len(proxy.Bypass) > 0 {
+				contextArgs.SetProxyBypassList(proxy.bypassList())
+			}
+		}
+
+		bctx, err := bc.Target.CreateBrowserContext(ctx, contextArgs)
+		if err != nil {
+			return nil, err
+		}
+
+		cleanup = append(cleanup, func(ctx context.Context) {
+			_ = bc.Target.DisposeBrowserContext(ctx, target.NewDisposeBrowserContextArgs(bctx.BrowserContextID))
+		})
+
+		targetArgs.SetBrowserContextID(bctx.BrowserContextID)
+	}
+
+	t, err := bc.Target.CreateTarget(ctx, targetArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	cleanup = append(cleanup, func(ctx context.Context) {
+		_, _ = bc.Target.CloseTarget(ctx, target.NewCloseTargetArgs(t.TargetID))
+	})
+
+	var conn *rpcc.Conn
+
+	if b.pipe != nil {
+		session, err := bc.Target.AttachToTarget(ctx, target.NewAttachToTargetArgs(t.TargetID).SetFlatten(true))
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err = b.pipe.dial(ctx, string(session.SessionID))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		conn, err = rpcc.DialContext(ctx, fmt.Sprintf("ws://%s:%d/devtools/page/%s", b.host, b.port, t.TargetID))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cleanup = append(cleanup, func(context.Context) {
+		_ = conn.Close()
+	})
+
+	return &tab{
+		id:      string(t.TargetID),
+		conn:    conn,
+		closeFn: closeFn,
+	}, nil
+}
+
+// dialBrowser - connects to browser target of already running browser, its address is read from DevTools HTTP endpoint.
+func (b *browser) dialBrowser(ctx context.Context) error {
+	devt := devtool.New(fmt.Sprintf("http://%s:%s", b.host, strconv.Itoa(b.port)))
+
+	v, err := devt.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	if v.WebSocketDebuggerURL == "" {
+		return errors.New("browser does not report browser target address")
+	}
+
+	b.conn, err = rpcc.DialContext(ctx, v.WebSocketDebuggerURL)
+
+	return err
+}
+
+// newDevToolsPage - opens new page target using DevTools HTTP endpoint of already running browser.
+func (b *browser) newDevToolsPage(ctx context.Context) (*tab, error) {
+	devt := devtool.New(fmt.Sprintf("http://%s:%s", b.host, strconv.Itoa(b.port)))
+
+	pt, err := devt.Create(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := rpcc.DialContext(ctx, pt.WebSocketDebuggerURL)
+	if err != nil {
+		_ = devt.Close(ctx, pt)
+
+		return nil, err
+	}
+
+	return &tab{
+		id:   pt.ID,
+		conn: conn,
+		closeFn: func() {
+			_ = conn.Close()
+			_ = devt.Close(ctx, pt)
+		},
+	}, nil
+}
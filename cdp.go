@@ -4,57 +4,111 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strconv"
+	"log/slog"
 	"time"
 
 	"github.com/mafredri/cdp"
-	"github.com/mafredri/cdp/devtool"
 	"github.com/mafredri/cdp/protocol/dom"
 	"github.com/mafredri/cdp/protocol/emulation"
 	"github.com/mafredri/cdp/protocol/network"
 	"github.com/mafredri/cdp/protocol/page"
 	"github.com/mafredri/cdp/protocol/security"
-	"github.com/mafredri/cdp/rpcc"
 )
 
-// CDPScreenshot - low-level function that creates screenshot for URL using CDP.
+// CDPScreenshot - low-level function that creates screenshot for URL using CDP
+// exposed by already running browser on Host:Port.
 func (c *Config) CDPScreenshot(ctx context.Context) ([]byte, error) {
-	var (
-		width, height float64
-		format        string = "png"
-	)
+	r, err := c.CDPCapture(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	devt := devtool.New(fmt.Sprintf("http://%s:%s", c.Host, strconv.Itoa(c.Port)))
+	return r.Image, nil
+}
 
-LOOP:
-	for {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-			time.Sleep(100 * time.Millisecond)
+// CDPCapture - low-level function like CDPScreenshot, returns screenshot with recording and metadata.
+// With Proxy set page is opened in separate browser context routed through proxy, so it does not share
+// cookies and storage with default browser context.
+func (c *Config) CDPCapture(ctx context.Context) (*Result, error) {
+	b := &browser{
+		host: c.Host,
+		port: c.Port,
+		log:  c.logger(),
+	}
 
-			_, err := devt.List(ctx)
-			if err == nil {
-				break LOOP
-			}
+	if c.Proxy.enabled() {
+		// running browser was not started with proxy flags, so page is opened in browser context using proxy,
+		// which is created over browser target connection
+		err := b.dialBrowser(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to CDP for URL='%s': %w", c.URL, err)
 		}
+		defer b.conn.Close()
 	}
 
-	pt, err := devt.Create(ctx)
+	t, err := b.newPage(ctx, c.Proxy.enabled(), c.Proxy)
 	if err != nil {
-		return nil, fmt.Errorf("failed to start CDP for URL='%s': %s", c.URL, err.Error())
+		return nil, fmt.Errorf("failed to connect to CDP for URL='%s': %w", c.URL, err)
 	}
-	defer devt.Close(ctx, pt)
+	defer t.close()
+	defer cdp.NewClient(t.conn).Browser.Close(ctx)
 
-	conn, err := rpcc.DialContext(ctx, pt.WebSocketDebuggerURL)
+	return c.capture(ctx, t)
+}
+
+// errTargetCrashed - renderer process of page target died during capture.
+var errTargetCrashed = errors.New("page target crashed")
+
+// capture - navigates page target to URL and captures screenshot, capture is aborted when page renderer dies.
+func (c *Config) capture(ctx context.Context, t *tab) (*Result, error) {
+	if c.Deterministic && (c.AutoScroll || c.DismissConsent || len(c.Actions) > 0) {
+		return nil, fmt.Errorf("failed to capture URL='%s': %w", c.URL, errDeterministicSteps)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	client := cdp.NewClient(t.conn)
+
+	crashed, err := client.Inspector.TargetCrashed(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to CDP for URL='%s': %s", c.URL, err.Error())
+		return nil, fmt.Errorf("failed to watch page target for URL='%s': %w", c.URL, err)
 	}
-	defer conn.Close()
+	defer crashed.Close()
 
-	cdp := cdp.NewClient(conn)
-	defer cdp.Browser.Close(ctx)
+	err = client.Inspector.Enable(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable Inspector for URL='%s': %w", c.URL, err)
+	}
+
+	go func() {
+		if _, err := crashed.Recv(); err == nil {
+			cancel(errTargetCrashed)
+		}
+	}()
+
+	r, err := c.capturePage(ctx, client, t)
+	if err != nil && errors.Is(context.Cause(ctx), errTargetCrashed) {
+		return nil, fmt.Errorf("failed to capture URL='%s': %w", c.URL, errTargetCrashed)
+	}
+
+	// calls cut short by expired context may report closed connection instead of context error
+	if err != nil && ctx.Err() != nil && !errors.Is(err, ctx.Err()) {
+		err = fmt.Errorf("%w: %w", ctx.Err(), err)
+	}
+
+	return r, err
+}
+
+// capturePage - navigates page target to URL and captures screenshot using page session client.
+func (c *Config) capturePage(ctx context.Context, cdp *cdp.Client, t *tab) (*Result, error) {
+	var (
+		width, height float64
+		format        string = "png"
+	)
+
+	log := c.logger().With(slog.String("target_id", t.id))
+	log.Debug("page target opened")
 
 	// disable unused services
 	services := []struct {
@@ -63,7 +117,6 @@ LOOP:
 	}{
 		{"Debugger", cdp.Debugger.Disable},
 		{"HeapProfiler", cdp.HeapProfiler.Disable},
-		{"Inspector", cdp.Inspector.Disable},
 		{"LayerTree", cdp.LayerTree.Disable},
 		{"Log", cdp.Log.Disable},
 		{"Overlay", cdp.Overlay.Disable},
@@ -73,41 +126,60 @@ LOOP:
 
 	for _, service := range services {
 		if err := service.fn(ctx); err != nil {
-			return nil, fmt.Errorf("failed to disable %s for URL='%s': %s", service.name, c.URL, err.Error())
+			return nil, fmt.Errorf("failed to disable %s for URL='%s': %w", service.name, c.URL, err)
+		}
+	}
+
+	err := cdp.Network.Enable(ctx, &network.EnableArgs{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable network events for URL='%s': %w", c.URL, err)
+	}
+
+	if c.Network.enabled() {
+		err = c.emulateNetwork(ctx, cdp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to emulate network conditions for URL='%s': %w", c.URL, err)
 		}
+
+		log.Debug("network conditions emulated",
+			slog.Bool("offline", c.Network.Offline),
+			slog.Duration("latency", c.Network.Latency),
+			slog.Int("download_throughput", c.Network.DownloadThroughput),
+			slog.Int("upload_throughput", c.Network.UploadThroughput),
+		)
 	}
 
-	err = cdp.Network.Enable(ctx, &network.EnableArgs{})
+	err = c.handleProxyAuth(ctx, cdp, log)
 	if err != nil {
-		return nil, fmt.Errorf("failed to enable network events for URL='%s': %s", c.URL, err.Error())
+		return nil, fmt.Errorf("failed to set up proxy authentication for URL='%s': %w", c.URL, err)
 	}
 
 	_ = page.NewSetAdBlockingEnabledArgs(true)
 
 	domContent, err := cdp.Page.DOMContentEventFired(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to catch DOMContentEventFired for URL='%s': %s", c.URL, err.Error())
+		return nil, fmt.Errorf("failed to catch DOMContentEventFired for URL='%s': %w", c.URL, err)
 	}
 	defer domContent.Close()
 
 	err = cdp.Page.Enable(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to enable Page events for URL='%s': %s", c.URL, err.Error())
+		return nil, fmt.Errorf("failed to enable Page events for URL='%s': %w", c.URL, err)
 	}
 
 	err = cdp.DOM.Enable(ctx, &dom.EnableArgs{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to enable DOM events for URL='%s': %s", c.URL, err.Error())
+		return nil, fmt.Errorf("failed to enable DOM events for URL='%s': %w", c.URL, err)
 	}
 
 	err = cdp.CSS.Enable(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to enable CSS events for URL='%s': %s", c.URL, err.Error())
+		return nil, fmt.Errorf("failed to enable CSS events for URL='%s': %w", c.URL, err)
 	}
 
 	err = cdp.Emulation.ClearDeviceMetricsOverride(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to clear Device Metrics Override for URL='%s': %s", c.URL, err.Error())
+		return nil, fmt.Errorf("failed to clear Device Metrics Override for URL='%s': %w", c.URL, err)
 	}
 
 	err = cdp.Emulation.SetDeviceMetricsOverride(ctx, &emulation.SetDeviceMetricsOverrideArgs{
@@ -117,44 +189,110 @@ LOOP:
 		Mobile:            false,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to set Device Metrics Overrides for URL='%s': %s", c.URL, err.Error())
+		return nil, fmt.Errorf("failed to set Device Metrics Overrides for URL='%s': %w", c.URL, err)
+	}
+
+	if c.cpuThrottlingRate() > 1 {
+		err = cdp.Emulation.SetCPUThrottlingRate(ctx, emulation.NewSetCPUThrottlingRateArgs(c.cpuThrottlingRate()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to set CPU throttling rate for URL='%s': %w", c.URL, err)
+		}
+
+		log.Debug("CPU throttled", slog.Float64("rate", c.cpuThrottlingRate()))
 	}
 
 	err = cdp.Security.SetIgnoreCertificateErrors(ctx, &security.SetIgnoreCertificateErrorsArgs{
 		Ignore: true,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to set Ignore Certificate errors option for URL='%s': %s", c.URL, err.Error())
+		return nil, fmt.Errorf("failed to set Ignore Certificate errors option for URL='%s': %w", c.URL, err)
 	}
 
 	loadEventFired, err := cdp.Page.LoadEventFired(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to catch page Load Event fired for URL='%s': %s", c.URL, err.Error())
+		return nil, fmt.Errorf("failed to catch page Load Event fired for URL='%s': %w", c.URL, err)
 	}
 	defer loadEventFired.Close()
 
+	var rec *recorder
+
+	if c.Record != RecordNone {
+		rec, err = startRecording(ctx, cdp, c.WindowWidth, c.WindowHeight)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start screencast for URL='%s': %w", c.URL, err)
+		}
+
+		log.Debug("screencast started", slog.String("format", string(c.Record)))
+	}
+
+	var budgetExpired <-chan error
+
+	if c.Deterministic {
+		budgetExpired, err = c.startDeterministic(ctx, cdp, log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to enable deterministic mode for URL='%s': %w", c.URL, err)
+		}
+	}
+
+	log.Debug("navigating")
+
+	navStart := time.Now()
+
 	nav, err := cdp.Page.Navigate(ctx, page.NewNavigateArgs(c.URL))
 	if err != nil {
-		return nil, fmt.Errorf("failed to Navigate to URL='%s': %s", c.URL, err.Error())
+		return nil, fmt.Errorf("failed to Navigate to URL='%s': %w", c.URL, err)
 	}
 
 	_, err = domContent.Recv()
 	if err != nil {
-		return nil, fmt.Errorf("failed to receive DOM content for URL='%s': %s", c.URL, err.Error())
+		return nil, fmt.Errorf("failed to receive DOM content for URL='%s': %w", c.URL, err)
 	}
 
+	log.Debug("DOMContentLoaded received", slog.Duration("duration", time.Since(navStart)))
+
 	_, err = loadEventFired.Recv()
 	if err != nil {
-		return nil, fmt.Errorf("failed to receive Load Event fired for URL='%s': %s", c.URL, err.Error())
+		return nil, fmt.Errorf("failed to receive Load Event fired for URL='%s': %w", c.URL, err)
 	}
 
+	log.Debug("load event received", slog.Duration("duration", time.Since(navStart)))
+
 	if nav.ErrorText != nil {
+		log.Info("navigation failed", slog.String("error", *nav.ErrorText))
+
 		return nil, fmt.Errorf("failed to Navigate to URL='%s': %s", c.URL, errors.New(*nav.ErrorText))
 	}
 
+	log.Info("page loaded", slog.Duration("duration", time.Since(navStart)))
+
+	if c.AutoScroll {
+		err = c.autoScroll(ctx, cdp, log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to auto scroll page for URL='%s': %w", c.URL, err)
+		}
+	}
+
+	var consent string
+
+	if c.DismissConsent {
+		consent, err = c.dismissConsent(ctx, cdp, log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to dismiss consent dialog for URL='%s': %w", c.URL, err)
+		}
+	}
+
+	if len(c.Actions) > 0 {
+		err = c.runActions(ctx, cdp, log)
+		if err != nil {
+			log.Info("action failed", slog.String("error", err.Error()))
+
+			return nil, fmt.Errorf("failed to run actions for URL='%s': %w", c.URL, err)
+		}
+	}
+
 	layout, err := cdp.Page.GetLayoutMetrics(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get Layout Metrics for URL='%s': %s", c.URL, err.Error())
+		return nil, fmt.Errorf("failed to get Layout Metrics for URL='%s': %w", c.URL, err)
 	}
 
 	if c.FullPage {
@@ -173,71 +311,243 @@ LOOP:
 			Mobile:            false,
 		})
 		if err != nil {
-			return nil, fmt.Errorf("failed to set full page device metrics for URL='%s': %s", c.URL, err.Error())
+			return nil, fmt.Errorf("failed to set full page device metrics for URL='%s': %w", c.URL, err)
 		}
 
 		_, err = cdp.DOM.GetDocument(ctx, &dom.GetDocumentArgs{})
 		if err != nil {
-			return nil, fmt.Errorf("failed to force layout recalculation for URL='%s': %s", c.URL, err.Error())
+			return nil, fmt.Errorf("failed to force layout recalculation for URL='%s': %w", c.URL, err)
 		}
 	}
 
-	done := make(chan bool)
-	var lastError error
+	var (
+		done        chan bool
+		timeoutChan <-chan time.Time
+		lastError   error
+	)
 
-	go func() {
-		defer close(done)
+	// in deterministic mode page is settled once virtual time budget is spent
+	if !c.Deterministic {
+		done = make(chan bool)
 
-		loadingFinished, err := cdp.Network.LoadingFinished(ctx)
-		if err != nil {
-			lastError = fmt.Errorf("failed to create loading finished listener: %v", err)
-			return
-		}
-		defer loadingFinished.Close()
+		go func() {
+			defer close(done)
 
-		if _, err := loadingFinished.Recv(); err != nil {
-			lastError = fmt.Errorf("failed waiting for network idle: %v", err)
-			return
-		}
-	}()
+			loadingFinished, err := cdp.Network.LoadingFinished(ctx)
+			if err != nil {
+				lastError = fmt.Errorf("failed to create loading finished listener: %w", err)
+				return
+			}
+			defer loadingFinished.Close()
 
-	var timeoutChan <-chan time.Time
-	if c.Wait > 0 {
-		timeoutChan = time.After(c.Wait)
+			if _, err := loadingFinished.Recv(); err != nil {
+				lastError = fmt.Errorf("failed waiting for network idle: %w", err)
+				return
+			}
+		}()
+
+		if c.Wait > 0 {
+			timeoutChan = time.After(c.Wait)
+		}
 	}
 
+	waitStart := time.Now()
+
 	select {
 	case <-done:
 		if lastError != nil {
+			log.Info("wait failed", slog.String("error", lastError.Error()))
+
 			return nil, lastError
 		}
+
+		log.Debug("wait finished", slog.String("outcome", "network idle"), slog.Duration("duration", time.Since(waitStart)))
 	case <-ctx.Done():
+		log.Info("wait aborted", slog.String("error", ctx.Err().Error()))
+
 		return nil, ctx.Err()
 	case <-timeoutChan:
 		// only reached if c.Wait > 0
+		log.Debug("wait finished", slog.String("outcome", "timeout"), slog.Duration("duration", time.Since(waitStart)))
+	case err = <-budgetExpired:
+		// only reached in deterministic mode
+		if err != nil {
+			log.Info("wait failed", slog.String("error", err.Error()))
+
+			return nil, fmt.Errorf("failed waiting for virtual time budget for URL='%s': %w", c.URL, err)
+		}
+
+		log.Debug("wait finished", slog.String("outcome", "virtual time budget"), slog.Duration("duration", time.Since(waitStart)))
+	}
+
+	frameTree, err := cdp.Page.GetFrameTree(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get frame tree for URL='%s': %w", c.URL, err)
 	}
 
+	// redirects and script navigation change main frame URL
+	finalURL := frameTree.FrameTree.Frame.URL
+	if frameTree.FrameTree.Frame.URLFragment != nil {
+		finalURL += *frameTree.FrameTree.Frame.URLFragment
+	}
+
+	if finalURL == "" {
+		finalURL = c.URL
+	}
+
+	var axTree []byte
+
+	if c.Accessibility {
+		axTree, err = accessibilityTree(ctx, cdp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get accessibility tree for URL='%s': %w", c.URL, err)
+		}
+
+		log.Debug("accessibility tree exported", slog.Int("bytes", len(axTree)))
+	}
+
+	var (
+		frames    []Frame
+		animation []byte
+	)
+
+	if rec != nil {
+		frames, err = rec.stop(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stop screencast for URL='%s': %w", c.URL, err)
+		}
+
+		switch c.Record {
+		case RecordFrames:
+		case RecordGIF:
+			animation, err = encodeGIF(frames)
+		case RecordAPNG:
+			animation, err = encodeAPNG(frames)
+		default:
+			err = fmt.Errorf("unknown record format %q", c.Record)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode screencast for URL='%s': %w", c.URL, err)
+		}
+
+		log.Debug("screencast stopped", slog.Int("frames", len(frames)), slog.Int("bytes", len(animation)))
+	}
+
+	clip := c.clip(width, height)
+
 	screenshotArgs := page.NewCaptureScreenshotArgs().
 		SetFormat(format).
-		SetClip(
-			page.Viewport{
-				X:      0 + float64(c.PaddingLeft),
-				Y:      0 + float64(c.PaddingTop),
-				Width:  width + float64(c.PaddingRight),
-				Height: height + float64(c.PaddingBottom),
-				Scale:  1,
-			},
-		)
+		SetClip(clip)
+
+	if !c.Clip.empty() {
+		// explicit clip may lie outside of viewport
+		screenshotArgs.SetCaptureBeyondViewport(true)
+	}
 
 	err = cdp.Page.StopLoading(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to stop Page loading for URL='%s': %s", c.URL, err.Error())
+		return nil, fmt.Errorf("failed to stop Page loading for URL='%s': %w", c.URL, err)
 	}
 
+	var restoreBackground func(context.Context) error
+
+	if c.OmitBackground {
+		restoreBackground, err = omitBackground(ctx, cdp, format)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set transparent background for URL='%s': %w", c.URL, err)
+		}
+	}
+
+	captureStart := time.Now()
+
 	scr, err := cdp.Page.CaptureScreenshot(ctx, screenshotArgs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to Capture Screenshot for URL='%s': %s", c.URL, err.Error())
+		return nil, fmt.Errorf("failed to Capture Screenshot for URL='%s': %w", c.URL, err)
+	}
+
+	img, width, height, err := c.finish(scr.Data, clip.Width, clip.Height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finish screenshot for URL='%s': %w", c.URL, err)
+	}
+
+	log.Info("screenshot captured",
+		slog.Float64("width", width),
+		slog.Float64("height", height),
+		slog.Int("bytes", len(img)),
+		slog.Duration("duration", time.Since(captureStart)),
+	)
+
+	var viewports []ViewportImage
+
+	if len(c.Viewports) > 0 {
+		viewports, err = c.captureViewports(ctx, cdp, log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to capture viewports for URL='%s': %w", c.URL, err)
+		}
+	}
+
+	if restoreBackground != nil {
+		err = restoreBackground(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore default background for URL='%s': %w", c.URL, err)
+		}
+	}
+
+	r := &Result{
+		URL:        c.URL,
+		FinalURL:   finalURL,
+		CapturedAt: captureStart.UTC(),
+		Image:      img,
+		Width:      width,
+		Height:     height,
+		Viewports:  viewports,
+		Frames:     frames,
+		Animation:  animation,
+
+		Accessibility: axTree,
+		Consent:       consent,
+
+		CPUThrottlingRate: c.cpuThrottlingRate(),
+	}
+
+	if len(c.PostProcess) > 0 {
+		err = c.postProcess(ctx, r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to post-process screenshot for URL='%s': %w", c.URL, err)
+		}
+
+		log.Debug("screenshot post-processed", slog.Int("processors", len(c.PostProcess)), slog.Int("bytes", len(r.Image)))
+	}
+
+	return r, nil
+}
+
+// finish - applies padding and output size to captured image, returns image with its size. Main image and
+// viewport images are finished the same way.
+func (c *Config) finish(img []byte, width, height float64) ([]byte, float64, float64, error) {
+	var err error
+
+	if c.hasPadding() {
+		img, err = c.pad(img)
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("failed to pad image: %w", err)
+		}
+
+		width += float64(max(c.PaddingLeft, 0) + max(c.PaddingRight, 0))
+		height += float64(max(c.PaddingTop, 0) + max(c.PaddingBottom, 0))
+	}
+
+	if c.hasOutputSize() {
+		var w, h int
+
+		img, w, h, err = c.scale(img)
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("failed to scale image: %w", err)
+		}
+
+		width, height = float64(w), float64(h)
 	}
 
-	return scr.Data, nil
+	return img, width, height, nil
 }
@@ -0,0 +1,84 @@
+package screenshot
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+
+	"github.com/mafredri/cdp/protocol/page"
+)
+
+// Clip - page area in CSS pixels relative to top left corner of document, zero size means whole captured area.
+type Clip struct {
+	X, Y          float64
+	Width, Height float64
+}
+
+// empty - reports whether clip selects no area.
+func (c Clip) empty() bool {
+	return c.Width <= 0 || c.Height <= 0
+}
+
+// clip - returns screenshot clip for captured area of width and height, explicit Config.Clip takes precedence.
+func (c *Config) clip(width, height float64) page.Viewport {
+	if !c.Clip.empty() {
+		return page.Viewport{
+			X:      c.Clip.X,
+			Y:      c.Clip.Y,
+			Width:  c.Clip.Width,
+			Height: c.Clip.Height,
+			Scale:  1,
+		}
+	}
+
+	return page.Viewport{
+		X:      0,
+		Y:      0,
+		Width:  width,
+		Height: height,
+		Scale:  1,
+	}
+}
+
+// hasPadding - reports whether captured image is extended by padding.
+func (c *Config) hasPadding() bool {
+	return c.PaddingTop > 0 || c.PaddingBottom > 0 || c.PaddingLeft > 0 || c.PaddingRight > 0
+}
+
+// paddingColor - returns colour of padding, white when it is not set.
+func (c *Config) paddingColor() color.Color {
+	if c.PaddingColor != nil {
+		return c.PaddingColor
+	}
+
+	return color.White
+}
+
+// pad - extends PNG image canvas by padding filled with padding colour, image is kept at its original scale.
+func (c *Config) pad(img []byte) ([]byte, error) {
+	src, err := png.Decode(bytes.NewReader(img))
+	if err != nil {
+		return nil, err
+	}
+
+	left, top := max(c.PaddingLeft, 0), max(c.PaddingTop, 0)
+
+	canvas := image.NewRGBA(image.Rect(0, 0,
+		left+src.Bounds().Dx()+max(c.PaddingRight, 0),
+		top+src.Bounds().Dy()+max(c.PaddingBottom, 0),
+	))
+
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(c.paddingColor()), image.Point{}, draw.Src)
+	draw.Draw(canvas, src.Bounds().Sub(src.Bounds().Min).Add(image.Pt(left, top)), src, src.Bounds().Min, draw.Src)
+
+	var buf bytes.Buffer
+
+	err = png.Encode(&buf, canvas)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
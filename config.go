@@ -1,6 +1,10 @@
 package screenshot
 
 import (
+	"image/color"
+	"io"
+	"log/slog"
+	"slices"
 	"time"
 )
 
@@ -16,22 +20,63 @@ type Config struct {
 
 	Flags []string
 
-	FullPage bool
+	Proxy   Proxy
+	Network NetworkConditions
+
+	CPUThrottlingRate float64
+
+	FullPage   bool
+	AutoScroll bool
+
+	Accessibility bool
+
+	DismissConsent bool
+
+	Actions []Action
+
+	Record RecordFormat
 
 	RandomProfileDir bool
 
+	Pipe bool
+
 	Port int
 
 	WindowWidth  int
 	WindowHeight int
 
+	Viewports []Viewport
+
 	PaddingTop    int
 	PaddingBottom int
 	PaddingLeft   int
 	PaddingRight  int
+	PaddingColor  color.Color
+
+	Clip Clip
+
+	OmitBackground bool
+
+	OutputWidth  int
+	OutputHeight int
+	ScaleMode    ScaleMode
+
+	PostProcess []Processor
 
 	Wait            time.Duration
 	ContextDeadline time.Duration
+
+	Deterministic     bool
+	VirtualTimeBudget time.Duration
+
+	// memory is limited by cgroup only when memory controller is delegated to current process cgroup,
+	// otherwise RLIMIT_DATA limits each browser process separately
+	MemoryLimit    int64
+	CPUTimeLimit   time.Duration
+	OpenFilesLimit uint64
+
+	BrowserOutput io.Writer
+	Logger        *slog.Logger
 }
 
 // DefaultConfig - creates structure with default values.
@@ -78,3 +123,15 @@ func DefaultConfig() Config {
 		},
 	}
 }
+
+// clone - returns deep copy of config, used as per-run state.
+func (c *Config) clone() *Config {
+	out := *c
+	out.Flags = slices.Clone(c.Flags)
+	out.Viewports = slices.Clone(c.Viewports)
+	out.Proxy.Bypass = slices.Clone(c.Proxy.Bypass)
+	out.PostProcess = slices.Clone(c.PostProcess)
+	out.Actions = slices.Clone(c.Actions)
+
+	return &out
+}
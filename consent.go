@@ -0,0 +1,170 @@
+package screenshot
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mafredri/cdp"
+	"github.com/mafredri/cdp/protocol/page"
+	"github.com/mafredri/cdp/protocol/runtime"
+)
+
+const (
+	// consentTimeout - how long page is checked for consent dialog, dialogs are often shown shortly after load.
+	consentTimeout = 3 * time.Second
+
+	// consentPollInterval - how often page is checked for consent dialog.
+	consentPollInterval = 250 * time.Millisecond
+
+	// consentMarker - attribute that marks button found by consent rule, so it can be clicked through DOM and Input domains.
+	consentMarker = "data-go-webpage-screenshots-consent"
+)
+
+// consentRules - known consent frameworks, checked in order. Rule matches first visible element found by its selectors,
+// rule with texts matches only elements whose text equals one of them, earlier texts are preferred. Rule with containers
+// matches only elements inside element matching one of them, where consent dialogs live, so ordinary page buttons are
+// never clicked. Being inside fixed positioned element is not enough, sticky headers and chat widgets are fixed too.
+//
+//go:embed consent.json
+var consentRules []byte
+
+// consentExpression - marks accept button of first matching consent rule and returns rule name, or empty string.
+var consentExpression = fmt.Sprintf(`((rules, marker) => {
+	const visible = el => {
+		const style = getComputedStyle(el);
+		return el.getClientRects().length > 0 && style.visibility !== 'hidden' && style.display !== 'none';
+	};
+	const text = el => (el.innerText || el.value || '').replace(/\s+/g, ' ').trim().toLowerCase();
+	const contained = (rule, el) => !rule.containers || rule.containers.some(s => el.closest(s));
+
+	for (const rule of rules) {
+		const elements = rule.selectors.flatMap(s => Array.from(document.querySelectorAll(s)))
+			.filter(el => visible(el) && contained(rule, el));
+		let match = null;
+
+		if (rule.texts && rule.texts.length) {
+			for (const t of rule.texts) {
+				match = elements.find(el => text(el) === t);
+				if (match) {
+					break;
+				}
+			}
+		} else {
+			match = elements[0];
+		}
+
+		if (match) {
+			document.querySelectorAll('[' + marker + ']').forEach(el => el.removeAttribute(marker));
+			match.setAttribute(marker, '');
+			return rule.name;
+		}
+	}
+
+	return '';
+})(%s, %q)`, consentRules, consentMarker)
+
+// dismissConsent - clicks accept button of consent dialog and returns name of matched rule,
+// empty name is returned when no dialog is found before timeout.
+func (c *Config) dismissConsent(ctx context.Context, client *cdp.Client, log *slog.Logger) (string, error) {
+	start := time.Now()
+	deadline := start.Add(consentTimeout)
+
+	for {
+		reply, err := client.Runtime.Evaluate(ctx, runtime.NewEvaluateArgs(consentExpression).SetReturnByValue(true))
+		if err != nil {
+			return "", err
+		}
+
+		if reply.ExceptionDetails != nil {
+			return "", reply.ExceptionDetails
+		}
+
+		var rule string
+
+		err = json.Unmarshal(reply.Result.Value, &rule)
+		if err != nil {
+			return "", err
+		}
+
+		if rule != "" {
+			err = c.clickConsent(ctx, client)
+			if err != nil {
+				return "", fmt.Errorf("rule %s: %w", rule, err)
+			}
+
+			log.Debug("consent dismissed", slog.String("rule", rule), slog.Duration("duration", time.Since(start)))
+
+			return rule, nil
+		}
+
+		if time.Now().After(deadline) {
+			log.Debug("consent dialog not found", slog.Duration("duration", time.Since(start)))
+
+			return "", nil
+		}
+
+		select {
+		case <-time.After(consentPollInterval):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+// clickConsent - clicks marked consent button and lets page remove dialog, fails when click navigates page away,
+// e.g. when matched button submits a form, as captured page would not be the requested one.
+func (c *Config) clickConsent(ctx context.Context, client *cdp.Client) error {
+	before, err := client.Page.GetFrameTree(ctx)
+	if err != nil {
+		return err
+	}
+
+	main := before.FrameTree.Frame
+
+	requested, err := client.Page.FrameRequestedNavigation(ctx)
+	if err != nil {
+		return err
+	}
+	defer requested.Close()
+
+	err = runAction(ctx, client, Action{Kind: ActionClick, Selector: "[" + consentMarker + "]"})
+	if err != nil {
+		return err
+	}
+
+	// settling fails when click replaced document, navigation is reported then
+	settleErr := c.settleLayout(ctx, client)
+
+	// navigation is requested while click is dispatched, new document may still be loading
+drain:
+	for {
+		select {
+		case <-requested.Ready():
+			ev, err := requested.Recv()
+			if err != nil {
+				return err
+			}
+
+			if ev.FrameID == main.ID && ev.Disposition == page.ClientNavigationDispositionCurrentTab {
+				return fmt.Errorf("click navigated page from %q to %q", main.URL, ev.URL)
+			}
+		default:
+			break drain
+		}
+	}
+
+	after, err := client.Page.GetFrameTree(ctx)
+	if err != nil {
+		return err
+	}
+
+	if after.FrameTree.Frame.URL != main.URL {
+		return fmt.Errorf("click navigated page from %q to %q", main.URL, after.FrameTree.Frame.URL)
+	}
+
+	return settleErr
+}
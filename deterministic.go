@@ -0,0 +1,127 @@
+package screenshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mafredri/cdp"
+	"github.com/mafredri/cdp/protocol/animation"
+	"github.com/mafredri/cdp/protocol/emulation"
+	"github.com/mafredri/cdp/protocol/page"
+)
+
+const (
+	// defaultVirtualTimeBudget - virtual time page gets in deterministic mode when Config.VirtualTimeBudget is not set.
+	defaultVirtualTimeBudget = 5 * time.Second
+
+	// deterministicSeed - seed of Math.random replacement.
+	deterministicSeed = 0x5eed
+
+	// deterministicEpoch - Unix milliseconds returned by Date at navigation start, 2020-01-01T00:00:00Z.
+	deterministicEpoch = 1577836800000
+)
+
+// deterministicScript - evaluated before any page script: replaces Math.random with seeded mulberry32 generator,
+// pins Date to fixed epoch advancing with (virtual) page clock and hides text caret so it can not blink into image.
+var deterministicScript = fmt.Sprintf(`(() => {
+	let seed = %d;
+	Math.random = () => {
+		seed = (seed + 0x6d2b79f5) | 0;
+		let t = Math.imul(seed ^ (seed >>> 15), 1 | seed);
+		t = (t + Math.imul(t ^ (t >>> 7), 61 | t)) ^ t;
+		return ((t ^ (t >>> 14)) >>> 0) / 4294967296;
+	};
+
+	const epoch = %d;
+	const RealDate = Date;
+	const now = () => Math.floor(epoch + performance.now());
+	// function keeps plain Date() call returning string, class constructor would throw on it
+	const FakeDate = function Date(...args) {
+		if (!new.target) {
+			return new RealDate(now()).toString();
+		}
+		return Reflect.construct(RealDate, args.length === 0 ? [now()] : args, new.target);
+	};
+	Object.setPrototypeOf(FakeDate, RealDate);
+	Object.defineProperty(FakeDate, 'length', { value: RealDate.length });
+	FakeDate.prototype = RealDate.prototype;
+	Object.defineProperty(RealDate.prototype, 'constructor', { value: FakeDate, writable: true, configurable: true });
+	FakeDate.now = now;
+	Date = FakeDate;
+
+	const hideCaret = () => {
+		const style = document.createElement('style');
+		style.textContent = '*, *::before, *::after { caret-color: transparent !important; }';
+		(document.head || document.documentElement).appendChild(style);
+	};
+	if (document.readyState === 'loading') {
+		document.addEventListener('DOMContentLoaded', hideCaret, { once: true });
+	} else {
+		hideCaret();
+	}
+})()`, deterministicSeed, deterministicEpoch)
+
+// errDeterministicSteps - page steps that run after load wait for page timers, which stop once virtual time budget
+// is spent, so they can not be combined with deterministic mode.
+var errDeterministicSteps = errors.New("deterministic mode can not be combined with AutoScroll, DismissConsent or Actions")
+
+// virtualTimeBudget - returns configured virtual time budget or default one.
+func (c *Config) virtualTimeBudget() time.Duration {
+	if c.VirtualTimeBudget > 0 {
+		return c.VirtualTimeBudget
+	}
+
+	return defaultVirtualTimeBudget
+}
+
+// startDeterministic - prepares page for deterministic rendering, must be called before navigation.
+// Page timers run on virtual clock that advances instantly while no network fetches are pending,
+// returned channel receives once virtual time budget is spent and page clock is paused.
+func (c *Config) startDeterministic(ctx context.Context, client *cdp.Client, log *slog.Logger) (<-chan error, error) {
+	_, err := client.Page.AddScriptToEvaluateOnNewDocument(ctx, page.NewAddScriptToEvaluateOnNewDocumentArgs(deterministicScript))
+	if err != nil {
+		return nil, fmt.Errorf("failed to add deterministic init script: %w", err)
+	}
+
+	err = client.Animation.Enable(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable Animation domain: %w", err)
+	}
+
+	err = client.Animation.SetPlaybackRate(ctx, animation.NewSetPlaybackRateArgs(0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to pause animations: %w", err)
+	}
+
+	expired, err := client.Emulation.VirtualTimeBudgetExpired(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to catch VirtualTimeBudgetExpired: %w", err)
+	}
+
+	budget := c.virtualTimeBudget()
+
+	_, err = client.Emulation.SetVirtualTimePolicy(ctx, emulation.NewSetVirtualTimePolicyArgs(
+		emulation.VirtualTimePolicyPauseIfNetworkFetchesPending,
+	).SetBudget(float64(budget.Milliseconds())))
+	if err != nil {
+		_ = expired.Close()
+
+		return nil, fmt.Errorf("failed to set virtual time policy: %w", err)
+	}
+
+	log.Debug("deterministic mode enabled", slog.Duration("virtual_time_budget", budget))
+
+	done := make(chan error, 1)
+
+	go func() {
+		defer expired.Close()
+
+		_, err := expired.Recv()
+		done <- err
+	}()
+
+	return done, nil
+}
@@ -0,0 +1,61 @@
+package screenshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// devToolsActivePortFile - file written by Chrome into profile directory once DevTools endpoint is listening.
+const devToolsActivePortFile = "DevToolsActivePort"
+
+// readDevToolsActivePort - parses DevToolsActivePort file, returns TCP port picked by Chrome and browser target path.
+func readDevToolsActivePort(dir string) (int, string, error) {
+	b, err := os.ReadFile(filepath.Join(dir, devToolsActivePortFile))
+	if err != nil {
+		return 0, "", err
+	}
+
+	// file has two lines: port and browser target path, second line also guards against partial reads
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) < 2 || !strings.HasPrefix(lines[1], "/devtools/browser/") {
+		return 0, "", errors.New("incomplete DevToolsActivePort file")
+	}
+
+	port, err := strconv.Atoi(strings.TrimSpace(lines[0]))
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid port in DevToolsActivePort file: %w", err)
+	}
+
+	if port <= 0 || port > 65535 {
+		return 0, "", fmt.Errorf("invalid port in DevToolsActivePort file: %d", port)
+	}
+
+	return port, strings.TrimSpace(lines[1]), nil
+}
+
+// waitDevToolsActivePort - blocks until Chrome reports DevTools port and browser target path, browser exits or context is done.
+func waitDevToolsActivePort(ctx context.Context, dir string, exited <-chan struct{}) (int, string, error) {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		port, path, err := readDevToolsActivePort(dir)
+		if err == nil {
+			return port, path, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, "", ctx.Err()
+		case <-exited:
+			return 0, "", errors.New("browser exited before DevTools endpoint became ready")
+		case <-ticker.C:
+		}
+	}
+}
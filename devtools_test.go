@@ -0,0 +1,76 @@
+package screenshot
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadDevToolsActivePort(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		port    int
+		path    string
+		wantErr bool
+	}{
+		{name: "valid", content: "9222\n/devtools/browser/abc\n", port: 9222, path: "/devtools/browser/abc"},
+		{name: "CRLF", content: "9222\r\n/devtools/browser/abc\r\n", port: 9222, path: "/devtools/browser/abc"},
+		{name: "partial", content: "9222\n", wantErr: true},
+		{name: "partial path", content: "9222\n/devtools/", wantErr: true},
+		{name: "not a number", content: "port\n/devtools/browser/abc\n", wantErr: true},
+		{name: "out of range", content: "70000\n/devtools/browser/abc\n", wantErr: true},
+		{name: "empty", content: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			err := os.WriteFile(filepath.Join(dir, devToolsActivePortFile), []byte(tt.content), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			port, path, err := readDevToolsActivePort(dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+
+			if port != tt.port || path != tt.path {
+				t.Errorf("got %d %q, want %d %q", port, path, tt.port, tt.path)
+			}
+		})
+	}
+}
+
+func TestWaitDevToolsActivePort(t *testing.T) {
+	t.Run("written later", func(t *testing.T) {
+		dir := t.TempDir()
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			_ = os.WriteFile(filepath.Join(dir, devToolsActivePortFile), []byte("9222\n/devtools/browser/abc\n"), 0644)
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		port, _, err := waitDevToolsActivePort(ctx, dir, nil)
+		if err != nil || port != 9222 {
+			t.Errorf("got port %d, error %v", port, err)
+		}
+	})
+
+	t.Run("browser exited", func(t *testing.T) {
+		exited := make(chan struct{})
+		close(exited)
+
+		_, _, err := waitDevToolsActivePort(context.Background(), t.TempDir(), exited)
+		if err == nil {
+			t.Error("wait succeeded after browser exit")
+		}
+	})
+}
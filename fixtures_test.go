@@ -0,0 +1,193 @@
+package screenshot_test
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// Fixture pages served by newFixtureServer.
+const (
+	// pathSolid - viewport filled with colour from "color" query parameter (hex without '#', default ff0000).
+	pathSolid = "/solid"
+
+	// pathLong - page of "height" CSS pixels (default 5000), top half red and bottom half blue.
+	pathLong = "/long"
+
+	// pathSlow - green page that requests image served after "delay" (default 2s) once page is loaded.
+	pathSlow = "/slow"
+
+	// pathRedirect - redirects to pathSolid, keeping query.
+	pathRedirect = "/redirect"
+
+	// pathJSError - green page with script that throws on load.
+	pathJSError = "/js-error"
+
+	// pathNotFound - blue page rendered with 404 status.
+	pathNotFound = "/not-found"
+
+	// pathHang - never answers until client gives up.
+	pathHang = "/hang"
+
+	// pathRandom - page drawn from Math.random, Date and running animation, differs between loads unless
+	// rendered deterministically.
+	pathRandom = "/random"
+)
+
+// newFixtureServer - starts HTTP server with fixture pages for integration tests against real browser.
+func newFixtureServer() *httptest.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(pathSolid, func(w http.ResponseWriter, r *http.Request) {
+		writeHTML(w, http.StatusOK, fmt.Sprintf(
+			`<div style="width:100vw;height:100vh;background:#%s"></div>`,
+			query(r, "color", "ff0000"),
+		))
+	})
+
+	mux.HandleFunc(pathLong, func(w http.ResponseWriter, r *http.Request) {
+		height, err := strconv.Atoi(query(r, "height", "5000"))
+		if err != nil || height < 2 {
+			http.Error(w, "invalid height", http.StatusBadRequest)
+
+			return
+		}
+
+		writeHTML(w, http.StatusOK, fmt.Sprintf(
+			`<div style="height:%dpx;background:#ff0000"></div><div style="height:%dpx;background:#0000ff"></div>`,
+			height/2, height-height/2,
+		))
+	})
+
+	mux.HandleFunc(pathSlow, func(w http.ResponseWriter, r *http.Request) {
+		writeHTML(w, http.StatusOK, fmt.Sprintf(
+			`<div style="width:100vw;height:100vh;background:#00ff00"></div>`+
+				`<script>addEventListener("load", () => { new Image().src = "/slow-image?delay=%s"; });</script>`,
+			query(r, "delay", "2s"),
+		))
+	})
+
+	mux.HandleFunc("/slow-image", func(w http.ResponseWriter, r *http.Request) {
+		delay, err := time.ParseDuration(query(r, "delay", "2s"))
+		if err != nil {
+			http.Error(w, "invalid delay", http.StatusBadRequest)
+
+			return
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+
+		img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+		draw.Draw(img, img.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+		var buf bytes.Buffer
+		_ = png.Encode(&buf, img)
+
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(buf.Bytes())
+	})
+
+	mux.HandleFunc(pathRedirect, func(w http.ResponseWriter, r *http.Request) {
+		target := pathSolid
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+
+		http.Redirect(w, r, target, http.StatusFound)
+	})
+
+	mux.HandleFunc(pathJSError, func(w http.ResponseWriter, _ *http.Request) {
+		writeHTML(w, http.StatusOK,
+			`<div style="width:100vw;height:100vh;background:#00ff00"></div><script>throw new Error("fixture");</script>`,
+		)
+	})
+
+	mux.HandleFunc(pathNotFound, func(w http.ResponseWriter, _ *http.Request) {
+		writeHTML(w, http.StatusNotFound, `<div style="width:100vw;height:100vh;background:#0000ff">Not Found</div>`)
+	})
+
+	mux.HandleFunc(pathHang, func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+
+	mux.HandleFunc(pathRandom, func(w http.ResponseWriter, _ *http.Request) {
+		writeHTML(w, http.StatusOK,
+			`<style>@keyframes spin{to{transform:rotate(360deg)}}</style>`+
+				`<div id="box" style="height:300px"></div><p id="time" style="font:24px monospace"></p>`+
+				`<div style="width:100px;height:100px;background:#000;animation:spin 1s linear infinite"></div>`+
+				`<script>`+
+				`document.getElementById("box").style.background = "hsl(" + Math.floor(Math.random() * 360) + ",100%,50%)";`+
+				`setTimeout(() => { document.getElementById("time").textContent = new Date().toISOString() + " " + Date(); }, 1000);`+
+				`</script>`,
+		)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+// findChrome - returns path to Chrome or Chromium binary taken from CHROME_BIN environment variable
+// or found in PATH, integration tests are expected to skip when it is not found.
+func findChrome() (string, bool) {
+	if bin := os.Getenv("CHROME_BIN"); bin != "" {
+		if _, err := os.Stat(bin); err == nil {
+			return bin, true
+		}
+	}
+
+	for _, name := range []string{
+		"google-chrome-stable",
+		"google-chrome",
+		"chromium",
+		"chromium-browser",
+		"chrome",
+		"headless-shell",
+	} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, true
+		}
+	}
+
+	return "", false
+}
+
+// colorAt - decodes image and returns colour of pixel at x, y.
+func colorAt(img []byte, x, y int) (color.Color, error) {
+	m, _, err := image.Decode(bytes.NewReader(img))
+	if err != nil {
+		return nil, err
+	}
+
+	if !(image.Point{X: x, Y: y}).In(m.Bounds()) {
+		return nil, fmt.Errorf("point %d,%d is outside of image bounds %v", x, y, m.Bounds())
+	}
+
+	return m.At(x, y), nil
+}
+
+func query(r *http.Request, key, fallback string) string {
+	if v := r.URL.Query().Get(key); v != "" {
+		return v
+	}
+
+	return fallback
+}
+
+func writeHTML(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+
+	_, _ = fmt.Fprintf(w, `<!DOCTYPE html><html><head><style>html,body{margin:0;padding:0}</style></head><body>%s</body></html>`, body)
+}
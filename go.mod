@@ -4,12 +4,11 @@ go 1.23.4
 
 require (
 	github.com/corona10/goimagehash v1.1.0
+	github.com/gorilla/websocket v1.5.3
 	github.com/mafredri/cdp v0.35.0
+	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646
+	golang.org/x/image v0.24.0
 	golang.org/x/net v0.33.0
 )
 
-require (
-	github.com/gorilla/websocket v1.5.3 // indirect
-	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646 // indirect
-	golang.org/x/sync v0.10.0 // indirect
-)
+require golang.org/x/text v0.22.0 // indirect
@@ -0,0 +1,249 @@
+package screenshot_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	screenshot "github.com/s3rj1k/go-webpage-screenshots"
+)
+
+var (
+	red   = color.RGBA{R: 0xff, A: 0xff}
+	green = color.RGBA{G: 0xff, A: 0xff}
+	blue  = color.RGBA{B: 0xff, A: 0xff}
+)
+
+// chromeConfig - returns config for capturing fixture page with local Chrome, test is skipped without it.
+func chromeConfig(t *testing.T, url string) screenshot.Config {
+	t.Helper()
+
+	bin, ok := findChrome()
+	if !ok {
+		t.Skip("Chrome not found, set CHROME_BIN to run integration tests")
+	}
+
+	c := screenshot.DefaultConfig()
+	c.CMD = bin
+	c.URL = url
+	c.WindowWidth, c.WindowHeight = 800, 600
+	c.Wait = 500 * time.Millisecond
+	c.ContextDeadline = 30 * time.Second
+
+	return c
+}
+
+// checkImage - fails test when image size or colour of pixels differs from expected.
+func checkImage(t *testing.T, img []byte, width, height int, pixels map[image.Point]color.RGBA) {
+	t.Helper()
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(img))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Width != width || cfg.Height != height {
+		t.Errorf("image size %dx%d, want %dx%d", cfg.Width, cfg.Height, width, height)
+	}
+
+	for p, want := range pixels {
+		c, err := colorAt(img, p.X, p.Y)
+		if err != nil {
+			t.Error(err)
+
+			continue
+		}
+
+		if got := color.RGBAModel.Convert(c).(color.RGBA); got != want {
+			t.Errorf("pixel %v is %v, want %v", p, got, want)
+		}
+	}
+}
+
+func TestIntegrationPages(t *testing.T) {
+	srv := newFixtureServer()
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		path   string
+		config func(c *screenshot.Config)
+		width  int
+		height int
+		pixels map[image.Point]color.RGBA
+	}{
+		{
+			name:   "viewport",
+			path:   pathSolid + "?color=00ff00",
+			width:  800,
+			height: 600,
+			pixels: map[image.Point]color.RGBA{{0, 0}: green, {799, 599}: green},
+		},
+		{
+			name:   "FullPage",
+			path:   pathLong + "?height=3000",
+			config: func(c *screenshot.Config) { c.FullPage = true },
+			width:  800,
+			height: 3000,
+			pixels: map[image.Point]color.RGBA{{400, 10}: red, {400, 1490}: red, {400, 1510}: blue, {400, 2990}: blue},
+		},
+		{
+			name: "padding",
+			path: pathSolid,
+			config: func(c *screenshot.Config) {
+				c.PaddingTop, c.PaddingBottom, c.PaddingLeft, c.PaddingRight = 10, 20, 30, 40
+				c.PaddingColor = blue
+			},
+			width:  870,
+			height: 630,
+			pixels: map[image.Point]color.RGBA{{5, 5}: blue, {30, 10}: red, {829, 609}: red, {869, 629}: blue},
+		},
+		{
+			name:   "redirect",
+			path:   pathRedirect + "?color=0000ff",
+			width:  800,
+			height: 600,
+			pixels: map[image.Point]color.RGBA{{400, 300}: blue},
+		},
+		{
+			name:   "JS error",
+			path:   pathJSError,
+			width:  800,
+			height: 600,
+			pixels: map[image.Point]color.RGBA{{400, 300}: green},
+		},
+		{
+			// HTTP errors are rendered by browser like any other page
+			name:   "404",
+			path:   pathNotFound,
+			width:  800,
+			height: 600,
+			pixels: map[image.Point]color.RGBA{{400, 300}: blue, {799, 599}: blue},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := chromeConfig(t, srv.URL+tt.path)
+			if tt.config != nil {
+				tt.config(&c)
+			}
+
+			img, err := c.Screenshot()
+			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, screenshot.ErrResourceLimit) {
+				t.Fatalf("page was not rendered in time or killed browser: %v", err)
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			checkImage(t, img, tt.width, tt.height, tt.pixels)
+		})
+	}
+}
+
+func TestIntegrationWait(t *testing.T) {
+	srv := newFixtureServer()
+	defer srv.Close()
+
+	t.Run("timeout", func(t *testing.T) {
+		c := chromeConfig(t, srv.URL+pathSlow+"?delay=10s")
+		c.Wait = time.Second
+
+		start := time.Now()
+
+		img, err := c.Screenshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if d := time.Since(start); d >= 10*time.Second {
+			t.Errorf("capture took %s, Wait should end it before slow resource arrives", d)
+		}
+
+		checkImage(t, img, 800, 600, map[image.Point]color.RGBA{{400, 300}: green})
+	})
+
+	t.Run("network idle", func(t *testing.T) {
+		c := chromeConfig(t, srv.URL+pathSlow+"?delay=2s")
+		c.Wait = 0
+
+		start := time.Now()
+
+		_, err := c.Screenshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if d := time.Since(start); d < 2*time.Second {
+			t.Errorf("capture took %s, it should wait for slow resource", d)
+		}
+	})
+}
+
+func TestIntegrationHang(t *testing.T) {
+	srv := newFixtureServer()
+	defer srv.Close()
+
+	c := chromeConfig(t, srv.URL+pathHang)
+	c.ContextDeadline = 3 * time.Second
+
+	start := time.Now()
+
+	_, err := c.Screenshot()
+	if err == nil {
+		t.Fatal("Screenshot of page that never answers succeeded")
+	}
+
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, screenshot.ErrResourceLimit) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if d := time.Since(start); d > 3*time.Second+5*time.Second {
+		t.Errorf("capture took %s, deadline is %s", d, c.ContextDeadline)
+	}
+}
+
+func TestIntegrationDeterministic(t *testing.T) {
+	srv := newFixtureServer()
+	defer srv.Close()
+
+	c := chromeConfig(t, srv.URL+pathRandom)
+	c.Deterministic = true
+	c.VirtualTimeBudget = 2 * time.Second
+
+	first, err := c.Screenshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := c.Screenshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(first, second) {
+		t.Error("deterministic captures of the same page differ")
+	}
+}
+
+func TestIntegrationFinalURL(t *testing.T) {
+	srv := newFixtureServer()
+	defer srv.Close()
+
+	c := chromeConfig(t, srv.URL+pathRedirect+"?color=0000ff")
+
+	r, err := c.Capture()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := srv.URL + pathSolid + "?color=0000ff"; r.FinalURL != want {
+		t.Errorf("final URL %q, want %q", r.FinalURL, want)
+	}
+}
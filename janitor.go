@@ -0,0 +1,176 @@
+package screenshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ownerFile - marker written into profile directories created by this library.
+const ownerFile = ".go-webpage-screenshots-owner"
+
+// owner - content of ownerFile, identifies process that created profile directory and browser it started.
+type owner struct {
+	PID       int    `json:"pid"`
+	StartTime uint64 `json:"start_time"`
+	PGID      int    `json:"pgid"`
+
+	// PGIDStartTime - start time of browser process that leads process group
+	PGIDStartTime uint64 `json:"pgid_start_time,omitempty"`
+}
+
+// processStartTime - returns process start time in clock ticks since boot, used to detect PID reuse.
+func processStartTime(pid int) (uint64, error) {
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, err
+	}
+
+	// command name may contain spaces and parentheses, fields are counted after the last ')'
+	i := strings.LastIndexByte(string(b), ')')
+	if i < 0 {
+		return 0, errors.New("malformed process stat")
+	}
+
+	fields := strings.Fields(string(b[i+1:]))
+	if len(fields) < 20 {
+		return 0, errors.New("malformed process stat")
+	}
+
+	// starttime is field 22, which is 20th after command name
+	return strconv.ParseUint(fields[19], 10, 64)
+}
+
+// writeOwnerFile - marks profile directory as owned by current process and browser process group.
+func writeOwnerFile(dir string, pgid int) error {
+	start, err := processStartTime(os.Getpid())
+	if err != nil {
+		return err
+	}
+
+	o := owner{
+		PID:       os.Getpid(),
+		StartTime: start,
+		PGID:      pgid,
+	}
+
+	if pgid > 0 {
+		o.PGIDStartTime, err = processStartTime(pgid)
+		if err != nil {
+			return err
+		}
+	}
+
+	b, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, ownerFile), b, 0644)
+}
+
+// alive - reports whether process that created profile directory is still running.
+func (o owner) alive() bool {
+	start, err := processStartTime(o.PID)
+	if err != nil {
+		return false
+	}
+
+	return start == o.StartTime
+}
+
+// browserGroupAlive - reports whether browser process group is still running, processes of group are forked
+// by browser, so group with a process started before browser reused the ID and is not killed.
+func (o owner) browserGroupAlive() bool {
+	if o.PGID <= 0 || o.PGIDStartTime == 0 {
+		return false
+	}
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return false
+	}
+
+	alive := false
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		if gid, err := syscall.Getpgid(pid); err != nil || gid != o.PGID {
+			continue
+		}
+
+		start, err := processStartTime(pid)
+		if err != nil {
+			continue
+		}
+
+		// kernel does not hand out ID of existing group as PID, so leader with other start time means group was reused
+		if start < o.PGIDStartTime || (pid == o.PGID && start != o.PGIDStartTime) {
+			return false
+		}
+
+		alive = true
+	}
+
+	return alive
+}
+
+// Cleanup - removes stale profile directories created by this library in temporary directory
+// and kills browser process groups whose owner process is gone, e.g. after a crash, empty memory limit cgroups
+// left behind are removed too. Directories of running processes and directories without owner marker are left untouched.
+func Cleanup() error {
+	tmp := os.TempDir()
+
+	entries, err := os.ReadDir(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to list temporary directory: %w", err)
+	}
+
+	var errs []error
+
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "cdp") {
+			continue
+		}
+
+		dir := filepath.Join(tmp, entry.Name())
+
+		b, err := os.ReadFile(filepath.Join(dir, ownerFile))
+		if err != nil {
+			continue
+		}
+
+		var o owner
+
+		if err := json.Unmarshal(b, &o); err != nil || o.alive() {
+			continue
+		}
+
+		if o.browserGroupAlive() {
+			if err := syscall.Kill(-o.PGID, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+				errs = append(errs, fmt.Errorf("failed to kill browser process group %d: %w", o.PGID, err))
+
+				continue
+			}
+		}
+
+		if err := os.RemoveAll(dir); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove stale profile directory %s: %w", dir, err))
+		}
+	}
+
+	if err := removeStaleCgroups(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,337 @@
+package screenshot
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mafredri/cdp"
+	"github.com/mafredri/cdp/protocol/target"
+)
+
+// ErrResourceLimit - returned (wrapped) when browser was killed for exceeding configured resource limits.
+var ErrResourceLimit = errors.New("browser exceeded resource limits")
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// hasLimits - reports whether any resource limit is configured.
+func (c *Config) hasLimits() bool {
+	return c.MemoryLimit > 0 || c.CPUTimeLimit > 0 || c.OpenFilesLimit > 0
+}
+
+// cgroup - cgroup v2 sub-group created for single browser process group.
+type cgroup struct {
+	dir string
+	fd  *os.File
+}
+
+// cgroupStaleAge - minimal age of empty cgroup removed by Cleanup, younger one may belong to browser being started.
+const cgroupStaleAge = time.Minute
+
+// cgroupParent - returns cgroup v2 directory of current process, browser cgroups are created inside it.
+func cgroupParent() (string, error) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return "", err
+	}
+
+	b, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(string(b), "\n") {
+		if self, ok := strings.CutPrefix(line, "0::"); ok && self != "" {
+			return filepath.Join(cgroupRoot, self), nil
+		}
+	}
+
+	return "", errors.New("cgroup v2 membership not found")
+}
+
+// newCgroup - creates cgroup v2 sub-group with memory limit inside current process cgroup, returns nil when
+// cgroup v2 is not mounted or memory controller is not already delegated to sub-groups of current process cgroup.
+// Controller is never enabled here: cgroup with member processes can not have controllers enabled (outside root),
+// and enabling it in root would change system wide configuration. Without delegation, e.g. by systemd
+// Delegate=memory with process moved to leaf sub-group, memory limit falls back to per-process RLIMIT_DATA.
+func newCgroup(memoryLimit int64) *cgroup {
+	parent, err := cgroupParent()
+	if err != nil {
+		return nil
+	}
+
+	controllers, err := os.ReadFile(filepath.Join(parent, "cgroup.subtree_control"))
+	if err != nil || !slices.Contains(strings.Fields(string(controllers)), "memory") {
+		return nil
+	}
+
+	dir, err := os.MkdirTemp(parent, "cdp")
+	if err != nil {
+		return nil
+	}
+
+	cg := &cgroup{dir: dir}
+
+	err = os.WriteFile(filepath.Join(dir, "memory.max"), []byte(strconv.FormatInt(memoryLimit, 10)), 0644)
+	if err != nil {
+		cg.remove()
+
+		return nil
+	}
+
+	// swap would let browser grow past memory.max without being killed
+	_ = os.WriteFile(filepath.Join(dir, "memory.swap.max"), []byte("0"), 0644)
+
+	cg.fd, err = os.Open(dir)
+	if err != nil {
+		cg.remove()
+
+		return nil
+	}
+
+	return cg
+}
+
+// oomKilled - reports whether kernel OOM killer killed any process inside cgroup.
+func (cg *cgroup) oomKilled() bool {
+	f, err := os.Open(filepath.Join(cg.dir, "memory.events"))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if count, ok := strings.CutPrefix(scanner.Text(), "oom_kill "); ok {
+			n, _ := strconv.Atoi(count)
+
+			return n > 0
+		}
+	}
+
+	return false
+}
+
+// remove - removes cgroup, it must be empty, so removal is retried while killed processes exit.
+func (cg *cgroup) remove() {
+	if cg.fd != nil {
+		_ = cg.fd.Close()
+	}
+
+	for range 10 {
+		if err := os.Remove(cg.dir); err == nil || errors.Is(err, os.ErrNotExist) {
+			return
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// removeStaleCgroups - removes empty browser cgroups left by processes that died before removing them,
+// cgroups with running processes can not be removed and are left untouched.
+func removeStaleCgroups() error {
+	parent, err := cgroupParent()
+	if err != nil {
+		return nil
+	}
+
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		return nil
+	}
+
+	var errs []error
+
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "cdp") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < cgroupStaleAge {
+			continue
+		}
+
+		err = os.Remove(filepath.Join(parent, entry.Name()))
+		if err != nil && !errors.Is(err, syscall.EBUSY) && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("failed to remove stale cgroup %s: %w", entry.Name(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// rlimitScript - returns shell script that sets rlimits and replaces itself with browser, so limits are in place
+// before browser runs and every process it forks inherits them, empty script means no rlimits are needed.
+// Memory limit is applied as RLIMIT_DATA only when cgroup is unavailable, RLIMIT_AS breaks V8 address space reservations.
+func (c *Config) rlimitScript(withCgroup bool) string {
+	var limits []string
+
+	if c.MemoryLimit > 0 && !withCgroup {
+		// ulimit takes data size in KiB
+		limits = append(limits, fmt.Sprintf("ulimit -d %d", max(c.MemoryLimit/1024, 1)))
+	}
+
+	if c.CPUTimeLimit > 0 {
+		// soft limit delivers SIGXCPU, hard limit one second later delivers SIGKILL
+		sec := int64(max(c.CPUTimeLimit.Round(time.Second)/time.Second, 1))
+
+		limits = append(limits, fmt.Sprintf("ulimit -t %d", sec+1), fmt.Sprintf("ulimit -S -t %d", sec))
+	}
+
+	if c.OpenFilesLimit > 0 {
+		limits = append(limits, fmt.Sprintf("ulimit -n %d", c.OpenFilesLimit))
+	}
+
+	if len(limits) == 0 {
+		return ""
+	}
+
+	// browser path and flags are passed as positional parameters, so they are never parsed by shell
+	return strings.Join(limits, " && ") + ` && exec "$0" "$@"`
+}
+
+// limitSet - resource limits browser was started with.
+type limitSet struct {
+	memory bool
+	cpu    time.Duration
+	files  bool
+}
+
+// any - reports whether any limit is set.
+func (l limitSet) any() bool {
+	return l.memory || l.cpu > 0 || l.files
+}
+
+// violated - returns name of set limit that explains process termination by signal or renderer termination status,
+// empty name is returned for terminations not caused by set limits, e.g. crashes. CPU time used by process is
+// negative when unknown.
+func (l limitSet) violated(status string, sig syscall.Signal, cpuTime time.Duration) string {
+	switch {
+	case l.memory && status == "oom":
+		return "memory"
+	case l.cpu > 0 && sig == syscall.SIGXCPU:
+		return "CPU time"
+	case l.cpu > 0 && sig == syscall.SIGKILL && cpuTime >= l.cpu:
+		// hard CPU limit, SIGKILL is also sent on deadline and by OOM killers, so it is blamed on CPU limit
+		// only when process really used that much CPU time
+		return "CPU time"
+	}
+
+	return ""
+}
+
+// targetCrash - termination of page renderer process reported by browser.
+type targetCrash struct {
+	status string
+	code   int
+}
+
+// watchCrashes - records renderer terminations reported by browser, renderer killed for exceeding its limits
+// leaves browser running, so this is the only place violation is seen.
+func (b *browser) watchCrashes(ctx context.Context) error {
+	client := cdp.NewClient(b.conn)
+
+	crashed, err := client.Target.TargetCrashed(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = client.Target.SetDiscoverTargets(ctx, target.NewSetDiscoverTargetsArgs(true))
+	if err != nil {
+		crashed.Close()
+
+		return err
+	}
+
+	go func() {
+		defer crashed.Close()
+
+		for {
+			ev, err := crashed.Recv()
+			if err != nil {
+				return
+			}
+
+			b.mu.Lock()
+			b.crashes[string(ev.TargetID)] = targetCrash{status: ev.Status, code: ev.ErrorCode}
+			b.mu.Unlock()
+		}
+	}()
+
+	return nil
+}
+
+// crash - returns recorded termination of page target, waiting shortly as browser reports it after page session does.
+func (b *browser) crash(targetID string) (targetCrash, bool) {
+	deadline := time.Now().Add(250 * time.Millisecond)
+
+	for {
+		b.mu.Lock()
+		crash, ok := b.crashes[targetID]
+		b.mu.Unlock()
+
+		if ok || time.Now().After(deadline) {
+			return crash, ok
+		}
+
+		time.Sleep(25 * time.Millisecond)
+	}
+}
+
+// limitError - wraps capture error with ErrResourceLimit when browser or renderer of page target
+// was killed for exceeding limits browser was started with, errors of done capture context are returned as is.
+func (b *browser) limitError(ctx context.Context, err error, targetID string) error {
+	if err == nil || !b.limits.any() {
+		return err
+	}
+
+	if b.cgroup != nil && b.cgroup.oomKilled() {
+		return fmt.Errorf("%w: memory: %w", ErrResourceLimit, err)
+	}
+
+	if targetID != "" && errors.Is(err, errTargetCrashed) {
+		crash, ok := b.crash(targetID)
+		if !ok {
+			return err
+		}
+
+		if name := b.limits.violated(crash.status, syscall.Signal(crash.code), -1); name != "" {
+			return fmt.Errorf("%w: %s: %w", ErrResourceLimit, name, err)
+		}
+
+		return err
+	}
+
+	// deadline kills browser with SIGKILL too, that is not a limit violation
+	if ctx.Err() != nil || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	// connection error usually arrives slightly before process exit is observed
+	select {
+	case <-b.exited:
+	case <-time.After(250 * time.Millisecond):
+		return err
+	}
+
+	status, ok := b.cmd.ProcessState.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return err
+	}
+
+	cpuTime := b.cmd.ProcessState.UserTime() + b.cmd.ProcessState.SystemTime()
+
+	if name := b.limits.violated("", status.Signal(), cpuTime); name != "" {
+		return fmt.Errorf("%w: %s: %w", ErrResourceLimit, name, err)
+	}
+
+	return err
+}
@@ -0,0 +1,69 @@
+package screenshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// signaledBrowser - returns browser with CPU time limit whose process was terminated by sig.
+func signaledBrowser(t *testing.T, sig syscall.Signal) *browser {
+	t.Helper()
+
+	cmd := exec.Command("sleep", "10")
+	if err := cmd.Start(); err != nil {
+		t.Skip(err)
+	}
+
+	_ = cmd.Process.Signal(sig)
+	_ = cmd.Wait()
+
+	b := &browser{cmd: cmd, exited: make(chan struct{}), limits: limitSet{cpu: time.Second}}
+	close(b.exited)
+
+	return b
+}
+
+func TestLimitError(t *testing.T) {
+	errConn := errors.New("connection closed")
+
+	expired, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+
+	tests := []struct {
+		name  string
+		sig   syscall.Signal
+		ctx   context.Context
+		err   error
+		limit bool
+	}{
+		{
+			name: "deadline with CPUTimeLimit set",
+			sig:  syscall.SIGKILL,
+			ctx:  context.Background(),
+			err:  fmt.Errorf("failed to capture screenshot: %w", context.DeadlineExceeded),
+		},
+		{name: "expired context with CPUTimeLimit set", sig: syscall.SIGKILL, ctx: expired, err: errConn},
+		{name: "SIGKILL below CPU time limit", sig: syscall.SIGKILL, ctx: context.Background(), err: errConn},
+		{name: "SIGXCPU", sig: syscall.SIGXCPU, ctx: context.Background(), err: errConn, limit: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := signaledBrowser(t, tt.sig)
+
+			err := b.limitError(tt.ctx, tt.err, "")
+			if !errors.Is(err, tt.err) {
+				t.Errorf("error %v does not wrap %v", err, tt.err)
+			}
+
+			if errors.Is(err, ErrResourceLimit) != tt.limit {
+				t.Errorf("error %v, want ErrResourceLimit %v", err, tt.limit)
+			}
+		})
+	}
+}
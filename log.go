@@ -0,0 +1,23 @@
+package screenshot
+
+import (
+	"context"
+	"log/slog"
+)
+
+// discardHandler - slog handler that drops all records, used when Config.Logger is not set.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (d discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return d }
+func (d discardHandler) WithGroup(string) slog.Handler           { return d }
+
+// logger - returns configured logger annotated with URL, or logger that discards everything.
+func (c *Config) logger() *slog.Logger {
+	if c.Logger == nil {
+		return slog.New(discardHandler{})
+	}
+
+	return c.Logger.With(slog.String("url", c.URL))
+}
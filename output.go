@@ -0,0 +1,125 @@
+package screenshot
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// browserOutputSize - amount of most recent browser output kept for diagnostics.
+const browserOutputSize = 16 * 1024
+
+// BrowserError - browser launch or connection failure, carries tail of browser stdout and stderr.
+type BrowserError struct {
+	Err    error
+	Output string
+}
+
+// Error - implements error interface.
+func (e *BrowserError) Error() string {
+	if e.Output == "" {
+		return e.Err.Error()
+	}
+
+	return fmt.Sprintf("%s\nbrowser output:\n%s", e.Err.Error(), e.Output)
+}
+
+// Unwrap - returns underlying error.
+func (e *BrowserError) Unwrap() error {
+	return e.Err
+}
+
+// ringBuffer - keeps last size bytes written to it, safe for concurrent use.
+type ringBuffer struct {
+	mu   sync.Mutex
+	size int
+	buf  []byte
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{
+		size: size,
+		buf:  make([]byte, 0, size),
+	}
+}
+
+// Write - implements io.Writer, never fails.
+func (r *ringBuffer) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := len(p)
+
+	if n >= r.size {
+		r.buf = append(r.buf[:0], p[n-r.size:]...)
+
+		return n, nil
+	}
+
+	if overflow := len(r.buf) + n - r.size; overflow > 0 {
+		r.buf = append(r.buf[:0], r.buf[overflow:]...)
+	}
+
+	r.buf = append(r.buf, p...)
+
+	return n, nil
+}
+
+// String - returns buffered output, first line is dropped when buffer has wrapped as it is likely cut.
+func (r *ringBuffer) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := string(r.buf)
+
+	if len(r.buf) == r.size {
+		if i := strings.IndexByte(s, '\n'); i >= 0 {
+			s = s[i+1:]
+		}
+	}
+
+	return strings.TrimSpace(s)
+}
+
+// outputWriter - copies browser output into ring buffer and optional caller writer,
+// caller writer errors are ignored so browser never blocks on full pipe.
+type outputWriter struct {
+	ring *ringBuffer
+	w    io.Writer
+}
+
+// Write - implements io.Writer.
+func (o outputWriter) Write(p []byte) (int, error) {
+	_, _ = o.ring.Write(p)
+
+	if o.w != nil {
+		_, _ = o.w.Write(p)
+	}
+
+	return len(p), nil
+}
+
+// captureOutput - creates pipe for browser stdout and stderr, copies everything written to it
+// into ring buffer and optional writer. Returned file must be closed by caller after process start,
+// returned channel is closed once all output is copied.
+func captureOutput(ring *ringBuffer, w io.Writer) (*os.File, <-chan struct{}, error) {
+	r, pw, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	drained := make(chan struct{})
+
+	// own pipe instead of exec copying goroutine, so Wait returns as soon as browser exits,
+	// even while its helper processes keep inherited descriptors open
+	go func() {
+		defer close(drained)
+		defer r.Close()
+
+		_, _ = io.Copy(outputWriter{ring: ring, w: w}, r)
+	}()
+
+	return pw, drained, nil
+}
@@ -0,0 +1,36 @@
+package screenshot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRingBuffer(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{name: "fits", writes: []string{"one\n", "two\n"}, want: "one\ntwo"},
+		{name: "wrapped drops cut line", writes: []string{"first line\n", "second\n", "third\n"}, want: "second\nthird"},
+		{name: "single write larger than buffer", writes: []string{"abcdefghij\nklmnopqrstuvwxyz\n12345\n"}, want: "12345"},
+		{name: "wrapped without newline", writes: []string{strings.Repeat("x", 40)}, want: strings.Repeat("x", 16)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRingBuffer(16)
+
+			for _, w := range tt.writes {
+				n, err := r.Write([]byte(w))
+				if err != nil || n != len(w) {
+					t.Fatalf("Write returned %d, %v", n, err)
+				}
+			}
+
+			if got := r.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
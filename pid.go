@@ -27,7 +27,8 @@ func (c *Config) KillByPGIDAndCleanup(cmd *exec.Cmd) {
 
 	pgid, err := syscall.Getpgid(cmd.Process.Pid)
 	if err != nil {
-		return
+		// group leader is already reaped, group ID matches its PID when started with Setpgid
+		pgid = cmd.Process.Pid
 	}
 
 	err = syscall.Kill(-pgid, syscall.SIGTERM)
@@ -0,0 +1,208 @@
+package screenshot
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/mafredri/cdp/rpcc"
+)
+
+// pipeConn - CDP transport over file descriptors opened by Chrome with --remote-debugging-pipe.
+// Chrome reads NUL terminated JSON messages on fd 3 and writes them on fd 4,
+// page sessions are multiplexed over the same pipe in flat mode using sessionId attribute.
+type pipeConn struct {
+	r io.ReadCloser
+	w io.WriteCloser
+
+	wmu sync.Mutex // protects w
+
+	mu       sync.Mutex // protects sessions
+	sessions map[string]*pipeSession
+
+	done chan struct{}
+	err  error // set before done is closed
+}
+
+// newPipeConn - creates pipe transport and starts reading messages from Chrome.
+func newPipeConn(r io.ReadCloser, w io.WriteCloser) *pipeConn {
+	p := &pipeConn{
+		r:        r,
+		w:        w,
+		sessions: make(map[string]*pipeSession),
+		done:     make(chan struct{}),
+	}
+
+	go p.read()
+
+	return p
+}
+
+// openPipes - creates pipe pairs, returned files for Chrome must be passed as fd 3 and fd 4.
+func openPipes() (chromeIn, chromeOut *os.File, p *pipeConn, err error) {
+	chromeIn, w, err := os.Pipe()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	r, chromeOut, err := os.Pipe()
+	if err != nil {
+		_ = chromeIn.Close()
+		_ = w.Close()
+
+		return nil, nil, nil, err
+	}
+
+	return chromeIn, chromeOut, newPipeConn(r, w), nil
+}
+
+func (p *pipeConn) read() {
+	br := bufio.NewReader(p.r)
+
+	for {
+		msg, err := br.ReadBytes(0)
+		if err != nil {
+			p.err = err
+			close(p.done)
+
+			return
+		}
+
+		msg = msg[:len(msg)-1]
+
+		var envelope struct {
+			SessionID string `json:"sessionId"`
+		}
+
+		if err := json.Unmarshal(msg, &envelope); err != nil {
+			continue
+		}
+
+		p.mu.Lock()
+		s, ok := p.sessions[envelope.SessionID]
+		p.mu.Unlock()
+
+		if !ok {
+			continue
+		}
+
+		select {
+		case s.recv <- msg:
+		case <-s.closed:
+		}
+	}
+}
+
+func (p *pipeConn) write(msg []byte) error {
+	p.wmu.Lock()
+	defer p.wmu.Unlock()
+
+	select {
+	case <-p.done:
+		return p.closedErr()
+	default:
+	}
+
+	_, err := p.w.Write(append(msg, 0))
+
+	return err
+}
+
+func (p *pipeConn) closedErr() error {
+	if p.err == nil || errors.Is(p.err, io.EOF) {
+		return errors.New("remote debugging pipe is closed")
+	}
+
+	return p.err
+}
+
+// dial - creates RPC connection for session, empty session ID addresses browser target.
+func (p *pipeConn) dial(ctx context.Context, sessionID string) (*rpcc.Conn, error) {
+	s := &pipeSession{
+		id:     sessionID,
+		p:      p,
+		recv:   make(chan []byte, 16),
+		closed: make(chan struct{}),
+	}
+
+	p.mu.Lock()
+	p.sessions[sessionID] = s
+	p.mu.Unlock()
+
+	return rpcc.DialContext(ctx, "pipe",
+		rpcc.WithDialer(func(context.Context, string) (io.ReadWriteCloser, error) {
+			return s, nil
+		}),
+		rpcc.WithCodec(func(io.ReadWriter) rpcc.Codec {
+			return s
+		}),
+	)
+}
+
+// Close - closes both ends of the pipe, Chrome exits when its end of the pipe is closed.
+func (p *pipeConn) Close() error {
+	return errors.Join(p.w.Close(), p.r.Close())
+}
+
+// pipeSession - single CDP session on pipe transport, implements rpcc.Codec.
+type pipeSession struct {
+	id string
+	p  *pipeConn
+
+	recv chan []byte
+
+	once   sync.Once
+	closed chan struct{}
+}
+
+// WriteRequest - implements rpcc.Codec.
+func (s *pipeSession) WriteRequest(r *rpcc.Request) error {
+	msg, err := json.Marshal(struct {
+		*rpcc.Request
+		SessionID string `json:"sessionId,omitempty"`
+	}{r, s.id})
+	if err != nil {
+		return err
+	}
+
+	return s.p.write(msg)
+}
+
+// ReadResponse - implements rpcc.Codec.
+func (s *pipeSession) ReadResponse(r *rpcc.Response) error {
+	select {
+	case msg := <-s.recv:
+		return json.Unmarshal(msg, r)
+	case <-s.closed:
+		return io.EOF
+	case <-s.p.done:
+		return s.p.closedErr()
+	}
+}
+
+// Read - transport is handled by codec, never called by rpcc.
+func (s *pipeSession) Read([]byte) (int, error) {
+	return 0, errors.New("pipe session does not support Read")
+}
+
+// Write - transport is handled by codec, never called by rpcc.
+func (s *pipeSession) Write([]byte) (int, error) {
+	return 0, errors.New("pipe session does not support Write")
+}
+
+// Close - detaches session from pipe transport, pipe itself stays open.
+func (s *pipeSession) Close() error {
+	s.once.Do(func() {
+		s.p.mu.Lock()
+		delete(s.p.sessions, s.id)
+		s.p.mu.Unlock()
+
+		close(s.closed)
+	})
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package screenshot
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/mafredri/cdp/rpcc"
+)
+
+// fakePipeBrowser - answers every request received over pipe with its session ID, like Chrome in flat mode.
+// Before each reply it sends event for session nobody dialed, which must be dropped without blocking.
+func fakePipeBrowser(t *testing.T, in io.Reader, out io.Writer) {
+	t.Helper()
+
+	br := bufio.NewReader(in)
+
+	for {
+		msg, err := br.ReadBytes(0)
+		if err != nil {
+			return
+		}
+
+		var req struct {
+			ID        uint64 `json:"id"`
+			SessionID string `json:"sessionId"`
+		}
+
+		if err := json.Unmarshal(msg[:len(msg)-1], &req); err != nil {
+			t.Errorf("malformed request %q: %v", msg, err)
+
+			return
+		}
+
+		_, err = fmt.Fprintf(out, `{"method":"Test.event","sessionId":"unknown","params":{}}`+"\x00"+
+			`{"id":%d,"sessionId":%q,"result":{"session":%q}}`+"\x00", req.ID, req.SessionID, req.SessionID)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func newTestPipe(t *testing.T) (*pipeConn, *io.PipeWriter) {
+	t.Helper()
+
+	fromBrowser, browserOut := io.Pipe()
+	browserIn, toBrowser := io.Pipe()
+
+	go fakePipeBrowser(t, browserIn, browserOut)
+
+	p := newPipeConn(fromBrowser, toBrowser)
+	t.Cleanup(func() { _ = p.Close() })
+
+	return p, browserOut
+}
+
+func TestPipeSessionRouting(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, _ := newTestPipe(t)
+
+	conns := make(map[string]*rpcc.Conn)
+
+	for _, id := range []string{"", "S1"} {
+		conn, err := p.dial(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		conns[id] = conn
+	}
+
+	// interleaved calls must each get reply addressed to own session
+	for range 3 {
+		for id, conn := range conns {
+			var reply struct {
+				Session string `json:"session"`
+			}
+
+			if err := rpcc.Invoke(ctx, "Test.method", nil, &reply, conn); err != nil {
+				t.Fatalf("session %q: %v", id, err)
+			}
+
+			if reply.Session != id {
+				t.Errorf("session %q got reply for session %q", id, reply.Session)
+			}
+		}
+	}
+}
+
+func TestPipeSessionClose(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, _ := newTestPipe(t)
+
+	conn, err := p.dial(ctx, "S1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	p.mu.Lock()
+	_, ok := p.sessions["S1"]
+	p.mu.Unlock()
+
+	if ok {
+		t.Error("closed session is still registered")
+	}
+
+	// replies for closed session are dropped, other sessions keep working
+	browser, err := p.dial(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer browser.Close()
+
+	if err := rpcc.Invoke(ctx, "Test.method", nil, nil, browser); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPipeEOF(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, browserOut := newTestPipe(t)
+
+	conn, err := p.dial(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// browser exit closes its end of the pipe
+	_ = browserOut.Close()
+
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		t.Fatal("pipe did not notice EOF")
+	}
+
+	if err := rpcc.Invoke(ctx, "Test.method", nil, nil, conn); err == nil {
+		t.Error("call succeeded on closed pipe")
+	}
+
+	if err := p.write([]byte(`{}`)); err == nil || err.Error() != "remote debugging pipe is closed" {
+		t.Errorf("write to closed pipe returned %v", err)
+	}
+}
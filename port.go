@@ -6,6 +6,9 @@ import (
 )
 
 // GetFreePort - returns free TCP port.
+//
+// Deprecated: port may be taken by another process before it is used,
+// Screenshot lets Chrome pick the port and reads it from DevToolsActivePort file instead.
 func GetFreePort() (int, error) {
 	addr, err := net.ResolveTCPAddr("tcp", "[::]:0")
 	if err != nil {
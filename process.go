@@ -0,0 +1,168 @@
+package screenshot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+)
+
+// Processor - transforms captured image, processors of Config.PostProcess run in order after capture on main image
+// and on every viewport image, each receives output of previous one and capture metadata with Image set to its input.
+type Processor interface {
+	Process(ctx context.Context, img []byte, meta Result) ([]byte, error)
+}
+
+// ProcessorFunc - adapter to use ordinary function as Processor.
+type ProcessorFunc func(ctx context.Context, img []byte, meta Result) ([]byte, error)
+
+// Process - implements Processor.
+func (f ProcessorFunc) Process(ctx context.Context, img []byte, meta Result) ([]byte, error) {
+	return f(ctx, img, meta)
+}
+
+// Resize - scales image to Width and Height in pixels using Mode, zero dimension is derived from aspect ratio.
+type Resize struct {
+	Width, Height int
+	Mode          ScaleMode
+}
+
+// Process - implements Processor.
+func (p Resize) Process(_ context.Context, img []byte, _ Result) ([]byte, error) {
+	src, err := decodeImage(img)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := scaleImage(src, p.Width, p.Height, p.Mode)
+	if err != nil {
+		return nil, err
+	}
+
+	return encodePNG(out, png.DefaultCompression)
+}
+
+// Crop - cuts rectangle given in image pixels, rectangle is limited to image bounds.
+type Crop struct {
+	X, Y          int
+	Width, Height int
+}
+
+// Process - implements Processor.
+func (p Crop) Process(_ context.Context, img []byte, _ Result) ([]byte, error) {
+	src, err := decodeImage(img)
+	if err != nil {
+		return nil, err
+	}
+
+	rect := image.Rect(p.X, p.Y, p.X+p.Width, p.Y+p.Height).Add(src.Bounds().Min).Intersect(src.Bounds())
+	if rect.Empty() {
+		return nil, fmt.Errorf("crop rectangle %d,%d %dx%d is outside of image bounds %v", p.X, p.Y, p.Width, p.Height, src.Bounds())
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(out, out.Bounds(), src, rect.Min, draw.Src)
+
+	return encodePNG(out, png.DefaultCompression)
+}
+
+// Grayscale - converts image to shades of grey.
+type Grayscale struct{}
+
+// Process - implements Processor.
+func (Grayscale) Process(_ context.Context, img []byte, _ Result) ([]byte, error) {
+	src, err := decodeImage(img)
+	if err != nil {
+		return nil, err
+	}
+
+	out := image.NewGray(image.Rect(0, 0, src.Bounds().Dx(), src.Bounds().Dy()))
+
+	for y := range out.Bounds().Dy() {
+		for x := range out.Bounds().Dx() {
+			out.Set(x, y, color.GrayModel.Convert(src.At(src.Bounds().Min.X+x, src.Bounds().Min.Y+y)))
+		}
+	}
+
+	return encodePNG(out, png.DefaultCompression)
+}
+
+// Recompress - re-encodes image as PNG with compression Level, png.BestCompression trades time for size.
+type Recompress struct {
+	Level png.CompressionLevel
+}
+
+// Process - implements Processor.
+func (p Recompress) Process(_ context.Context, img []byte, _ Result) ([]byte, error) {
+	src, err := decodeImage(img)
+	if err != nil {
+		return nil, err
+	}
+
+	return encodePNG(src, p.Level)
+}
+
+// postProcess - runs Config.PostProcess chain on captured image and on every viewport image,
+// metadata passed with viewport image describes that image.
+func (c *Config) postProcess(ctx context.Context, r *Result) error {
+	for i := range r.Viewports {
+		vp := &r.Viewports[i]
+
+		meta := *r
+		meta.Image, meta.Width, meta.Height, meta.Viewports = vp.Image, vp.Width, vp.Height, nil
+
+		err := c.processImage(ctx, &meta)
+		if err != nil {
+			return fmt.Errorf("viewport %dx%d: %w", vp.Viewport.Width, vp.Viewport.Height, err)
+		}
+
+		vp.Image, vp.Width, vp.Height = meta.Image, meta.Width, meta.Height
+	}
+
+	return c.processImage(ctx, r)
+}
+
+// processImage - runs Config.PostProcess chain on image of result and updates its size.
+func (c *Config) processImage(ctx context.Context, r *Result) error {
+	for i, p := range c.PostProcess {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		img, err := p.Process(ctx, r.Image, *r)
+		if err != nil {
+			return fmt.Errorf("processor %d (%T): %w", i, p, err)
+		}
+
+		r.Image = img
+	}
+
+	// processors may produce format without registered decoder, size is kept then
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(r.Image)); err == nil {
+		r.Width, r.Height = float64(cfg.Width), float64(cfg.Height)
+	}
+
+	return nil
+}
+
+// decodeImage - decodes image in any registered format.
+func decodeImage(img []byte) (image.Image, error) {
+	m, _, err := image.Decode(bytes.NewReader(img))
+
+	return m, err
+}
+
+// encodePNG - encodes image as PNG with compression level.
+func encodePNG(m image.Image, level png.CompressionLevel) ([]byte, error) {
+	var buf bytes.Buffer
+
+	err := (&png.Encoder{CompressionLevel: level}).Encode(&buf, m)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
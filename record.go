@@ -0,0 +1,167 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mafredri/cdp"
+	"github.com/mafredri/cdp/protocol/page"
+)
+
+// RecordFormat - output of screencast recording made while page loads.
+type RecordFormat string
+
+// Recording outputs, frames are returned in Result.Frames for every format,
+// animated formats are additionally encoded into Result.Animation. Long recordings are sampled down to 256 frames.
+const (
+	RecordNone   RecordFormat = ""
+	RecordFrames RecordFormat = "frames"
+	RecordGIF    RecordFormat = "gif"
+	RecordAPNG   RecordFormat = "apng"
+)
+
+// Frame - single PNG encoded screencast frame.
+type Frame struct {
+	Data      []byte
+	Timestamp time.Time
+}
+
+// WriteFrames - writes recorded frames into directory as numbered PNG files (frame-00001.png, ...).
+func (r *Result) WriteFrames(dir string) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	for i, frame := range r.Frames {
+		err = os.WriteFile(filepath.Join(dir, fmt.Sprintf("frame-%05d.png", i+1)), frame.Data, 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// maxRecordFrames - upper bound of frames kept by recorder. Longer recordings are sampled: when bound is reached
+// every other kept frame is dropped and from then on only every other received frame is kept, so recording
+// keeps covering whole page load while memory used by frames and their encoding stays bounded.
+const maxRecordFrames = 256
+
+// recorder - collects screencast frames from page.
+type recorder struct {
+	client *cdp.Client
+	stream page.ScreencastFrameClient
+
+	mu       sync.Mutex
+	frames   []Frame
+	received int   // frames received so far
+	step     int   // only every step-th received frame is kept
+	last     Frame // last received frame, it is kept even when sampling skips it
+
+	done chan struct{}
+}
+
+// add - keeps received frame when it falls on sampling step and halves kept frames once bound is reached.
+func (r *recorder) add(frame Frame) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.received%r.step == 0 {
+		r.frames = append(r.frames, frame)
+
+		if len(r.frames) >= maxRecordFrames {
+			kept := r.frames[:0]
+			for i := 0; i < len(r.frames); i += 2 {
+				kept = append(kept, r.frames[i])
+			}
+
+			clear(r.frames[len(kept):])
+			r.frames = kept
+			r.step *= 2
+		}
+	}
+
+	r.received++
+	r.last = frame
+}
+
+// recorded - returns kept frames ending with last received frame.
+func (r *recorder) recorded() []Frame {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.received == 0 {
+		return nil
+	}
+
+	// last frame shows final state of page, it is dropped by sampling unless it falls on sampling step
+	frames := r.frames
+	if len(frames) == 0 || !frames[len(frames)-1].Timestamp.Equal(r.last.Timestamp) {
+		frames = append(frames, r.last)
+	}
+
+	return frames
+}
+
+// startRecording - starts page screencast, every received frame is acknowledged so browser keeps sending them.
+func startRecording(ctx context.Context, client *cdp.Client, width, height int) (*recorder, error) {
+	stream, err := client.Page.ScreencastFrame(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.Page.StartScreencast(ctx, page.NewStartScreencastArgs().
+		SetFormat("png").
+		SetMaxWidth(width).
+		SetMaxHeight(height),
+	)
+	if err != nil {
+		_ = stream.Close()
+
+		return nil, err
+	}
+
+	r := &recorder{
+		client: client,
+		stream: stream,
+		step:   1,
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer close(r.done)
+
+		for {
+			ev, err := stream.Recv()
+			if err != nil {
+				return
+			}
+
+			ts := ev.Metadata.Timestamp.Time()
+			if ev.Metadata.Timestamp == 0 {
+				ts = time.Now()
+			}
+
+			r.add(Frame{Data: ev.Data, Timestamp: ts})
+
+			_ = client.Page.ScreencastFrameAck(ctx, page.NewScreencastFrameAckArgs(ev.SessionID))
+		}
+	}()
+
+	return r, nil
+}
+
+// stop - stops screencast and returns collected frames.
+func (r *recorder) stop(ctx context.Context) ([]Frame, error) {
+	err := r.client.Page.StopScreencast(ctx)
+
+	_ = r.stream.Close()
+	<-r.done
+
+	return r.recorded(), err
+}
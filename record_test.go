@@ -0,0 +1,44 @@
+package screenshot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecorderSampling(t *testing.T) {
+	start := time.Now()
+
+	for _, n := range []int{1, maxRecordFrames - 1, maxRecordFrames, 10 * maxRecordFrames, 10*maxRecordFrames + 1} {
+		r := &recorder{step: 1}
+
+		for i := range n {
+			r.add(Frame{Timestamp: start.Add(time.Duration(i) * time.Millisecond)})
+		}
+
+		frames := r.recorded()
+
+		if len(frames) > maxRecordFrames {
+			t.Errorf("%d received: %d frames kept, want at most %d", n, len(frames), maxRecordFrames)
+		}
+
+		if len(frames) < min(n, maxRecordFrames/2) {
+			t.Errorf("%d received: only %d frames kept", n, len(frames))
+		}
+
+		if !frames[0].Timestamp.Equal(start) {
+			t.Errorf("%d received: first frame dropped", n)
+		}
+
+		if last := start.Add(time.Duration(n-1) * time.Millisecond); !frames[len(frames)-1].Timestamp.Equal(last) {
+			t.Errorf("%d received: last frame dropped", n)
+		}
+
+		for i := 1; i < len(frames); i++ {
+			if !frames[i].Timestamp.After(frames[i-1].Timestamp) {
+				t.Errorf("%d received: frames out of order at %d", n, i)
+
+				break
+			}
+		}
+	}
+}
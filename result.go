@@ -0,0 +1,33 @@
+package screenshot
+
+import (
+	"time"
+)
+
+// Result - capture output together with metadata describing how it was produced.
+type Result struct {
+	URL string
+
+	// FinalURL - URL of main frame when page was captured, differs from URL after redirects.
+	FinalURL string
+
+	CapturedAt time.Time
+
+	Image  []byte
+	Width  float64
+	Height float64
+
+	Viewports []ViewportImage
+
+	Frames    []Frame
+	Animation []byte
+
+	// Accessibility - JSON encoded []AXNode, set when Config.Accessibility is enabled.
+	Accessibility []byte
+
+	// Consent - name of consent rule whose dialog was dismissed, empty when none matched.
+	Consent string
+
+	// CPUThrottlingRate - CPU slowdown factor page was rendered with, 1 means no throttling.
+	CPUThrottlingRate float64
+}
@@ -0,0 +1,93 @@
+package screenshot
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+
+	"github.com/nfnt/resize"
+)
+
+// ScaleMode - how captured image is scaled to output size.
+type ScaleMode string
+
+// Scale modes, when only one of output dimensions is set image is scaled proportionally to it in every mode.
+const (
+	// ScaleFit - scales image to fit inside output size keeping aspect ratio, result may be smaller than output size.
+	ScaleFit ScaleMode = ""
+
+	// ScaleFill - stretches image to exact output size.
+	ScaleFill ScaleMode = "fill"
+
+	// ScaleCrop - scales image to cover output size keeping aspect ratio and crops overflow, keeping top center part.
+	ScaleCrop ScaleMode = "crop"
+)
+
+// scaleImage - scales image to width and height according to mode, zero dimension is derived from aspect ratio.
+func scaleImage(src image.Image, width, height int, mode ScaleMode) (image.Image, error) {
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+
+	if width <= 0 && height <= 0 || srcWidth == 0 || srcHeight == 0 {
+		return src, nil
+	}
+
+	if width <= 0 || height <= 0 {
+		// resize derives missing dimension from aspect ratio
+		return resize.Resize(uint(max(width, 0)), uint(max(height, 0)), src, resize.Lanczos3), nil
+	}
+
+	switch mode {
+	case ScaleFit:
+		return resize.Thumbnail(uint(width), uint(height), src, resize.Lanczos3), nil
+	case ScaleFill:
+		return resize.Resize(uint(width), uint(height), src, resize.Lanczos3), nil
+	case ScaleCrop:
+		// scale by larger ratio so image covers output size
+		ratio := max(float64(width)/float64(srcWidth), float64(height)/float64(srcHeight))
+
+		scaled := resize.Resize(
+			uint(max(width, int(float64(srcWidth)*ratio+0.5))),
+			uint(max(height, int(float64(srcHeight)*ratio+0.5))),
+			src, resize.Lanczos3,
+		)
+
+		x := scaled.Bounds().Min.X + (scaled.Bounds().Dx()-width)/2
+		y := scaled.Bounds().Min.Y
+
+		out := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(out, out.Bounds(), scaled, image.Pt(x, y), draw.Src)
+
+		return out, nil
+	}
+
+	return nil, fmt.Errorf("unknown scale mode %q", mode)
+}
+
+// hasOutputSize - reports whether captured image is scaled to output size.
+func (c *Config) hasOutputSize() bool {
+	return c.OutputWidth > 0 || c.OutputHeight > 0
+}
+
+// scale - scales PNG image to configured output size, returns scaled image with its size.
+func (c *Config) scale(img []byte) ([]byte, int, int, error) {
+	src, err := png.Decode(bytes.NewReader(img))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	out, err := scaleImage(src, c.OutputWidth, c.OutputHeight, c.ScaleMode)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	var buf bytes.Buffer
+
+	err = png.Encode(&buf, out)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	return buf.Bytes(), out.Bounds().Dx(), out.Bounds().Dy(), nil
+}
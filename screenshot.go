@@ -3,41 +3,52 @@ package screenshot
 import (
 	"context"
 	"fmt"
-	"io"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"slices"
-	"strconv"
-	"syscall"
-	"time"
 )
 
 // Screenshot - makes screenshot for URL, returns raw slice bytes.
+// Receiver is not modified, so the same Config can be used from multiple goroutines.
 func (c *Config) Screenshot() ([]byte, error) {
-	var err error
+	r, err := c.Capture()
+	if err != nil {
+		return nil, err
+	}
 
-	if c.RandomProfileDir {
-		c.ProfileDir, err = os.MkdirTemp(os.TempDir(), "cdp")
-		if err != nil {
-			return nil, fmt.Errorf("failed to get temporary directory for URL=%q: %w", c.URL, err)
-		}
-	} else {
-		c.ProfileDir = filepath.Join(os.TempDir(), "cdp")
+	return r.Image, nil
+}
+
+// Capture - makes screenshot for URL, returns it with recording and metadata.
+// Receiver is not modified, so the same Config can be used from multiple goroutines.
+func (c *Config) Capture() (*Result, error) {
+	return c.clone().run()
+}
+
+// run - launches browser and captures URL, receiver must be private copy of config as it is modified.
+func (c *Config) run() (*Result, error) {
+	c.normalize()
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.ContextDeadline)
+	defer cancel()
 
-		err = os.MkdirAll(c.ProfileDir, 0666)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get temporary directory for URL=%q: %w", c.URL, err)
-		}
+	b, err := c.launch(ctx)
+	if err != nil {
+		return nil, err
 	}
+	defer b.close(c)
 
-	if c.Port == 0 {
-		c.Port, err = GetFreePort()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get free TCP port for URL=%q: %w", c.URL, err)
-		}
+	// browser was launched with proxy flags, page inherits them, default context keeps persistent profile cookies visible
+	t, err := b.newPage(ctx, false, Proxy{})
+	if err != nil {
+		return nil, b.limitError(ctx, b.outputError(fmt.Errorf("failed to open page for URL=%q: %w", c.URL, err)), "")
 	}
+	defer t.close()
+
+	r, err := c.capture(ctx, t)
+
+	return r, b.limitError(ctx, err, t.id)
+}
 
+// normalize - clamps window size to supported range.
+func (c *Config) normalize() {
 	// https://en.wikipedia.org/wiki/8K_resolution
 	if c.WindowWidth > 8192 {
 		c.WindowWidth = 8192
@@ -51,46 +62,4 @@ func (c *Config) Screenshot() ([]byte, error) {
 	if c.WindowHeight < 50 {
 		c.WindowHeight = 50
 	}
-
-	flags := slices.Clone(c.Flags)
-
-	flags = append(flags, []string{
-		fmt.Sprintf("--remote-debugging-port=%s", strconv.Itoa(c.Port)),
-		fmt.Sprintf("--window-size=%d,%d", c.WindowWidth, c.WindowHeight),
-	}...)
-
-	if c.AcceptLanguage != "" {
-		flags = append(flags, fmt.Sprintf("--lang=%s", c.AcceptLanguage))
-	}
-
-	if c.UserAgent != "" {
-		flags = append(flags, fmt.Sprintf("--user-agent=%s", c.UserAgent))
-	}
-
-	if len(c.ProfileDir) > 0 {
-		flags = append(flags, fmt.Sprintf("--user-data-dir=%s", c.ProfileDir))
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), c.ContextDeadline)
-	defer cancel()
-
-	cmd := exec.CommandContext(ctx, c.CMD, flags...)
-
-	cmd.Stdout = io.Discard
-	cmd.Stderr = io.Discard
-
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		Setpgid:   true,
-		Pdeathsig: syscall.SIGKILL,
-	}
-
-	err = cmd.Start()
-	if err != nil {
-		return nil, fmt.Errorf("failed to start CDP for url=%s: %s", c.URL, err.Error())
-	}
-
-	time.Sleep(500 * time.Millisecond) // cooldown, make time for linux to actually start process
-	defer c.KillByPGIDAndCleanup(cmd)
-
-	return c.CDPScreenshot(ctx)
 }
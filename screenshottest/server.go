@@ -0,0 +1,453 @@
+// Package screenshottest provides in-process fake DevTools endpoint for testing code built on screenshot package without Chrome.
+package screenshottest
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mafredri/cdp/devtool"
+	"github.com/mafredri/cdp/protocol/dom"
+	"github.com/mafredri/cdp/protocol/emulation"
+	"github.com/mafredri/cdp/protocol/network"
+	"github.com/mafredri/cdp/protocol/page"
+	"github.com/mafredri/cdp/protocol/target"
+
+	screenshot "github.com/s3rj1k/go-webpage-screenshots"
+)
+
+// browserPath - WebSocket path of browser target.
+const browserPath = "/devtools/browser/screenshottest"
+
+var (
+	// ErrNoReply - returned by Handler to leave command unanswered, simulates hung browser.
+	ErrNoReply = errors.New("screenshottest: no reply")
+
+	// ErrDisconnect - returned by Handler to drop WebSocket connection, simulates crashed browser.
+	ErrDisconnect = errors.New("screenshottest: disconnect")
+)
+
+// Handler - produces result for CDP command, any other returned error is sent to client as CDP error response.
+type Handler func(params json.RawMessage) (any, error)
+
+// Event - CDP event sent to client Delay after reply to command, events with equal Delay are sent in order.
+type Event struct {
+	Method string
+	Params any
+	Delay  time.Duration
+}
+
+// Call - CDP command received by Server.
+type Call struct {
+	Method string
+	Params json.RawMessage
+}
+
+// Server - fake DevTools HTTP and WebSocket endpoint answering commands issued by screenshot.Config.CDPScreenshot
+// to page targets and, when proxy is configured, to browser target.
+// Commands without handler are answered with empty result.
+type Server struct {
+	Host string
+	Port int
+
+	srv      *httptest.Server
+	upgrader websocket.Upgrader
+
+	mu       sync.Mutex // protects fields below
+	handlers map[string]Handler
+	events   map[string][]Event
+	calls    []Call
+	targets  int
+	conns    []*websocket.Conn
+
+	viewportWidth, viewportHeight float64
+	contentWidth, contentHeight   float64
+	fill                          color.Color
+}
+
+// NewServer - starts fake DevTools endpoint with default script of a page that loads successfully.
+func NewServer() *Server {
+	s := &Server{
+		handlers: make(map[string]Handler),
+		events:   make(map[string][]Event),
+
+		viewportWidth:  1920,
+		viewportHeight: 1080,
+		contentWidth:   1920,
+		contentHeight:  1080,
+		fill:           color.White,
+	}
+
+	s.defaults()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/json/version", s.handleVersion)
+	mux.HandleFunc("/json/new", s.handleNew)
+	mux.HandleFunc("/json/close/", s.handleClose)
+	mux.HandleFunc("/json/list", s.handleList)
+	mux.HandleFunc("/devtools/page/", s.handleWebSocket)
+	mux.HandleFunc("/devtools/browser/", s.handleWebSocket)
+
+	s.srv = httptest.NewServer(mux)
+
+	host, port, _ := net.SplitHostPort(s.srv.Listener.Addr().String())
+	s.Host = host
+	s.Port, _ = strconv.Atoi(port)
+
+	return s
+}
+
+// Close - shuts down server and drops all connections.
+func (s *Server) Close() {
+	s.mu.Lock()
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.srv.Close()
+}
+
+// Configure - points config to this server, for use with Config.CDPScreenshot.
+func (s *Server) Configure(c *screenshot.Config) {
+	c.Host = s.Host
+	c.Port = s.Port
+}
+
+// Handle - sets handler for CDP method, replacing default one.
+func (s *Server) Handle(method string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[method] = h
+}
+
+// Respond - answers CDP method with fixed result.
+func (s *Server) Respond(method string, result any) {
+	s.Handle(method, func(json.RawMessage) (any, error) {
+		return result, nil
+	})
+}
+
+// Fail - answers CDP method with error response carrying message.
+func (s *Server) Fail(method, message string) {
+	s.Handle(method, func(json.RawMessage) (any, error) {
+		return nil, errors.New(message)
+	})
+}
+
+// Emit - sets events sent after reply to CDP method, replacing default ones, no events are sent when empty.
+func (s *Server) Emit(method string, events ...Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events[method] = events
+}
+
+// SetContentSize - sets page content size reported by Page.getLayoutMetrics.
+func (s *Server) SetContentSize(width, height float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.contentWidth, s.contentHeight = width, height
+}
+
+// SetFill - sets colour of images returned by Page.captureScreenshot.
+func (s *Server) SetFill(c color.Color) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fill = c
+}
+
+// Calls - returns CDP commands received so far, in order.
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]Call, len(s.calls))
+	copy(out, s.calls)
+
+	return out
+}
+
+// Called - reports whether CDP method was received.
+func (s *Server) Called(method string) bool {
+	for _, call := range s.Calls() {
+		if call.Method == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+// defaults - installs script of successful page load.
+func (s *Server) defaults() {
+	s.handlers["Page.navigate"] = func(json.RawMessage) (any, error) {
+		return page.NavigateReply{FrameID: "frame", LoaderID: ptr(network.LoaderID("loader"))}, nil
+	}
+
+	s.events["Page.navigate"] = []Event{
+		{Method: "Page.domContentEventFired", Params: page.DOMContentEventFiredReply{Timestamp: now()}},
+		{Method: "Page.loadEventFired", Params: page.LoadEventFiredReply{Timestamp: now()}},
+	}
+
+	// capture subscribes to network events after layout metrics are read
+	s.events["Page.getLayoutMetrics"] = []Event{
+		{
+			Method: "Network.loadingFinished",
+			Params: network.LoadingFinishedReply{RequestID: "request", Timestamp: now()},
+			Delay:  50 * time.Millisecond,
+		},
+	}
+
+	s.handlers["Emulation.setDeviceMetricsOverride"] = func(params json.RawMessage) (any, error) {
+		var args emulation.SetDeviceMetricsOverrideArgs
+		if err := json.Unmarshal(params, &args); err != nil {
+			return nil, err
+		}
+
+		s.mu.Lock()
+		s.viewportWidth, s.viewportHeight = float64(args.Width), float64(args.Height)
+		s.mu.Unlock()
+
+		return struct{}{}, nil
+	}
+
+	s.handlers["Page.getLayoutMetrics"] = func(json.RawMessage) (any, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		layout := page.LayoutViewport{ClientWidth: int(s.viewportWidth), ClientHeight: int(s.viewportHeight)}
+		visual := page.VisualViewport{ClientWidth: s.viewportWidth, ClientHeight: s.viewportHeight, Scale: 1}
+		content := dom.Rect{Width: s.contentWidth, Height: s.contentHeight}
+
+		return page.GetLayoutMetricsReply{
+			LayoutViewport:    layout,
+			VisualViewport:    visual,
+			ContentSize:       content,
+			CSSLayoutViewport: layout,
+			CSSVisualViewport: visual,
+			CSSContentSize:    content,
+		}, nil
+	}
+
+	// browser target commands, pages are created in single fake browser context
+	s.handlers["Target.createBrowserContext"] = func(json.RawMessage) (any, error) {
+		return target.CreateBrowserContextReply{BrowserContextID: "context"}, nil
+	}
+
+	s.handlers["Target.createTarget"] = func(json.RawMessage) (any, error) {
+		return target.CreateTargetReply{TargetID: target.ID(s.newTarget())}, nil
+	}
+
+	s.handlers["DOM.getDocument"] = func(json.RawMessage) (any, error) {
+		return dom.GetDocumentReply{
+			Root: dom.Node{NodeID: 1, BackendNodeID: 1, NodeType: 9, NodeName: "#document"},
+		}, nil
+	}
+
+	s.handlers["Page.captureScreenshot"] = func(params json.RawMessage) (any, error) {
+		var args page.CaptureScreenshotArgs
+		if err := json.Unmarshal(params, &args); err != nil {
+			return nil, err
+		}
+
+		s.mu.Lock()
+		width, height, fill := s.viewportWidth, s.viewportHeight, s.fill
+		s.mu.Unlock()
+
+		if args.Clip != nil {
+			width, height = args.Clip.Width*args.Clip.Scale, args.Clip.Height*args.Clip.Scale
+		}
+
+		img := image.NewRGBA(image.Rect(0, 0, max(int(width), 1), max(int(height), 1)))
+		draw.Draw(img, img.Bounds(), image.NewUniform(fill), image.Point{}, draw.Src)
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+
+		return page.CaptureScreenshotReply{Data: buf.Bytes()}, nil
+	}
+}
+
+func (s *Server) target(id string) devtool.Target {
+	addr := s.srv.Listener.Addr().String()
+
+	return devtool.Target{
+		ID:                   id,
+		Type:                 devtool.Page,
+		URL:                  "about:blank",
+		WebSocketDebuggerURL: fmt.Sprintf("ws://%s/devtools/page/%s", addr, id),
+	}
+}
+
+func (s *Server) handleVersion(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, devtool.Version{
+		Browser:              "HeadlessChrome/screenshottest",
+		Protocol:             "1.3",
+		WebSocketDebuggerURL: fmt.Sprintf("ws://%s%s", s.srv.Listener.Addr().String(), browserPath),
+	})
+}
+
+func (s *Server) handleNew(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, s.target(s.newTarget()))
+}
+
+// newTarget - registers new page target and returns its ID.
+func (s *Server) newTarget() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.targets++
+
+	return fmt.Sprintf("target-%d", s.targets)
+}
+
+func (s *Server) handleClose(w http.ResponseWriter, _ *http.Request) {
+	_, _ = w.Write([]byte("Target is closing"))
+}
+
+func (s *Server) handleList(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	n := s.targets
+	s.mu.Unlock()
+
+	targets := make([]devtool.Target, 0, n)
+	for i := 1; i <= n; i++ {
+		targets = append(targets, s.target(fmt.Sprintf("target-%d", i)))
+	}
+
+	writeJSON(w, targets)
+}
+
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, "/devtools/page/target-") && r.URL.Path != browserPath {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.conns = append(s.conns, conn)
+	s.mu.Unlock()
+
+	c := &wsConn{conn: conn}
+	defer c.close()
+
+	for {
+		var req struct {
+			ID     uint64          `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.calls = append(s.calls, Call{Method: req.Method, Params: req.Params})
+		h := s.handlers[req.Method]
+		events := s.events[req.Method]
+		s.mu.Unlock()
+
+		var (
+			result any = struct{}{}
+			err    error
+		)
+
+		if h != nil {
+			result, err = h(req.Params)
+		}
+
+		switch {
+		case errors.Is(err, ErrNoReply):
+			continue
+		case errors.Is(err, ErrDisconnect):
+			return
+		case err != nil:
+			c.write(map[string]any{
+				"id":    req.ID,
+				"error": map[string]any{"code": -32000, "message": err.Error()},
+			})
+
+			continue
+		}
+
+		c.write(map[string]any{"id": req.ID, "result": result})
+
+		if len(events) > 0 {
+			go func(replied time.Time) {
+				for _, ev := range slices.SortedStableFunc(slices.Values(events), func(a, b Event) int {
+					return cmp.Compare(a.Delay, b.Delay)
+				}) {
+					time.Sleep(time.Until(replied.Add(ev.Delay)))
+					c.write(map[string]any{"method": ev.Method, "params": ev.Params})
+				}
+			}(time.Now())
+		}
+	}
+}
+
+// wsConn - serializes writes to WebSocket connection.
+type wsConn struct {
+	mu     sync.Mutex
+	conn   *websocket.Conn
+	closed bool
+}
+
+func (c *wsConn) write(v any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+
+	_ = c.conn.WriteJSON(v)
+}
+
+func (c *wsConn) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	_ = c.conn.Close()
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func now() network.MonotonicTime {
+	return network.MonotonicTime(float64(time.Now().UnixNano()) / float64(time.Second))
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,139 @@
+package screenshottest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/png"
+	"strings"
+	"testing"
+	"time"
+
+	screenshot "github.com/s3rj1k/go-webpage-screenshots"
+)
+
+func TestServerDefaultScript(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	s.SetContentSize(320, 480)
+	s.SetFill(color.RGBA{R: 0xff, A: 0xff})
+
+	c := screenshot.DefaultConfig()
+	s.Configure(&c)
+	c.URL = "http://example.com/"
+	c.WindowWidth, c.WindowHeight = 320, 240
+	c.FullPage = true
+
+	img, err := c.CDPScreenshot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, _, err := image.Decode(bytes.NewReader(img))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size := m.Bounds().Size(); size != image.Pt(320, 480) {
+		t.Errorf("image size %v, want 320x480", size)
+	}
+
+	if r, g, b, _ := m.At(160, 400).RGBA(); r != 0xffff || g != 0 || b != 0 {
+		t.Errorf("pixel colour %d,%d,%d, want red", r, g, b)
+	}
+
+	for _, method := range []string{"Page.navigate", "Emulation.setDeviceMetricsOverride", "Page.captureScreenshot"} {
+		if !s.Called(method) {
+			t.Errorf("%s was not called", method)
+		}
+	}
+
+	for _, call := range s.Calls() {
+		if call.Method != "Page.navigate" {
+			continue
+		}
+
+		var args struct {
+			URL string `json:"url"`
+		}
+
+		if err := json.Unmarshal(call.Params, &args); err != nil {
+			t.Fatal(err)
+		}
+
+		if args.URL != c.URL {
+			t.Errorf("navigated to %q, want %q", args.URL, c.URL)
+		}
+	}
+}
+
+func TestServerFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(s *Server)
+		wantErr func(ctx context.Context, err error) bool
+	}{
+		{
+			name: "Fail",
+			setup: func(s *Server) {
+				s.Fail("Page.navigate", "fixture navigation failure")
+			},
+			wantErr: func(_ context.Context, err error) bool {
+				return strings.Contains(err.Error(), "fixture navigation failure")
+			},
+		},
+		{
+			name: "ErrNoReply",
+			setup: func(s *Server) {
+				s.Handle("Page.captureScreenshot", func(json.RawMessage) (any, error) {
+					return nil, ErrNoReply
+				})
+			},
+			wantErr: func(ctx context.Context, _ error) bool {
+				// capture waits for reply until caller gives up
+				return errors.Is(ctx.Err(), context.DeadlineExceeded)
+			},
+		},
+		{
+			name: "ErrDisconnect",
+			setup: func(s *Server) {
+				s.Handle("Page.navigate", func(json.RawMessage) (any, error) {
+					return nil, ErrDisconnect
+				})
+			},
+			wantErr: func(ctx context.Context, _ error) bool {
+				// dropped connection fails capture right away
+				return ctx.Err() == nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer()
+			defer s.Close()
+
+			tt.setup(s)
+
+			c := screenshot.DefaultConfig()
+			s.Configure(&c)
+			c.URL = "http://example.com/"
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			img, err := c.CDPScreenshot(ctx)
+			if err == nil {
+				t.Fatalf("CDPScreenshot succeeded with %d bytes", len(img))
+			}
+
+			if !tt.wantErr(ctx, err) {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
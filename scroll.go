@@ -0,0 +1,210 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mafredri/cdp"
+	"github.com/mafredri/cdp/protocol/network"
+	"github.com/mafredri/cdp/protocol/runtime"
+)
+
+const (
+	// autoScrollStep - pause after every scroll step, lets lazy loading observers react.
+	autoScrollStep = 100 * time.Millisecond
+
+	// autoScrollMaxHeight - page is not scrolled past this offset, guards against infinite feeds.
+	autoScrollMaxHeight = 32768
+
+	// autoScrollQuiet - network is considered idle after this period without requests in flight.
+	autoScrollQuiet = 500 * time.Millisecond
+
+	// autoScrollTimeout - upper bound for waiting on requests triggered by scrolling.
+	autoScrollTimeout = 10 * time.Second
+)
+
+// autoScrollExpression - scrolls page down by viewport height until bottom (which may move as content loads) is reached.
+var autoScrollExpression = fmt.Sprintf(`(async () => {
+	const sleep = ms => new Promise(resolve => setTimeout(resolve, ms));
+	let y = 0;
+	while (y < Math.min(document.documentElement.scrollHeight, %d)) {
+		y += window.innerHeight;
+		window.scrollTo(0, y);
+		await sleep(%d);
+	}
+	return y;
+})()`, autoScrollMaxHeight, autoScrollStep.Milliseconds())
+
+// autoScroll - scrolls page to the bottom step by step to trigger lazy loaded content,
+// waits for requests caused by scrolling to finish and returns to the top.
+func (c *Config) autoScroll(ctx context.Context, client *cdp.Client, log *slog.Logger) error {
+	start := time.Now()
+
+	tracker, err := trackRequests(ctx, client)
+	if err != nil {
+		return err
+	}
+	defer tracker.close()
+
+	reply, err := client.Runtime.Evaluate(ctx, runtime.NewEvaluateArgs(autoScrollExpression).SetAwaitPromise(true))
+	if err != nil {
+		return err
+	}
+
+	if reply.ExceptionDetails != nil {
+		return reply.ExceptionDetails
+	}
+
+	idle := tracker.waitIdle(ctx, autoScrollQuiet, autoScrollTimeout)
+
+	reply, err = client.Runtime.Evaluate(ctx, runtime.NewEvaluateArgs(`window.scrollTo(0, 0)`))
+	if err != nil {
+		return err
+	}
+
+	if reply.ExceptionDetails != nil {
+		return reply.ExceptionDetails
+	}
+
+	log.Debug("page auto scrolled",
+		slog.Bool("network_idle", idle),
+		slog.Duration("duration", time.Since(start)),
+	)
+
+	return nil
+}
+
+// requestTracker - counts network requests in flight.
+type requestTracker struct {
+	mu       sync.Mutex
+	inflight map[network.RequestID]struct{}
+	changed  time.Time
+
+	streams []interface{ Close() error }
+	done    chan struct{} // closed when event goroutine exits, nil until it starts
+}
+
+// trackRequests - starts counting requests of page. Event streams are synchronized and consumed by single goroutine,
+// so request finished right after it was sent is never seen finished before it is seen sent.
+func trackRequests(ctx context.Context, client *cdp.Client) (*requestTracker, error) {
+	t := &requestTracker{
+		inflight: make(map[network.RequestID]struct{}),
+		changed:  time.Now(),
+	}
+
+	sent, err := client.Network.RequestWillBeSent(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t.streams = append(t.streams, sent)
+
+	finished, err := client.Network.LoadingFinished(ctx)
+	if err != nil {
+		t.close()
+
+		return nil, err
+	}
+	t.streams = append(t.streams, finished)
+
+	failed, err := client.Network.LoadingFailed(ctx)
+	if err != nil {
+		t.close()
+
+		return nil, err
+	}
+	t.streams = append(t.streams, failed)
+
+	err = cdp.Sync(sent, finished, failed)
+	if err != nil {
+		t.close()
+
+		return nil, err
+	}
+
+	t.done = make(chan struct{})
+
+	go func() {
+		defer close(t.done)
+
+		for {
+			var (
+				id      network.RequestID
+				started bool
+			)
+
+			select {
+			case <-sent.Ready():
+				ev, err := sent.Recv()
+				if err != nil {
+					return
+				}
+
+				id, started = ev.RequestID, true
+			case <-finished.Ready():
+				ev, err := finished.Recv()
+				if err != nil {
+					return
+				}
+
+				id = ev.RequestID
+			case <-failed.Ready():
+				ev, err := failed.Recv()
+				if err != nil {
+					return
+				}
+
+				id = ev.RequestID
+			}
+
+			t.mu.Lock()
+			if started {
+				t.inflight[id] = struct{}{}
+			} else {
+				delete(t.inflight, id)
+			}
+			t.changed = time.Now()
+			t.mu.Unlock()
+		}
+	}()
+
+	return t, nil
+}
+
+// waitIdle - waits until no request was in flight for quiet period, returns false on timeout.
+func (t *requestTracker) waitIdle(ctx context.Context, quiet, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		t.mu.Lock()
+		idle := len(t.inflight) == 0 && time.Since(t.changed) >= quiet
+		t.mu.Unlock()
+
+		if idle {
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-deadline:
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+func (t *requestTracker) close() {
+	for _, stream := range t.streams {
+		_ = stream.Close()
+	}
+
+	if t.done != nil {
+		<-t.done
+	}
+}
@@ -0,0 +1,90 @@
+package screenshot
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/mafredri/cdp"
+	"github.com/mafredri/cdp/protocol/network"
+)
+
+// NetworkConditions - emulated network connection of page, zero value leaves network unthrottled.
+type NetworkConditions struct {
+	Offline bool
+
+	// Latency - minimum time from request sent to response headers received.
+	Latency time.Duration
+
+	// DownloadThroughput, UploadThroughput - maximum aggregated throughput in bytes per second, zero disables throttling.
+	DownloadThroughput int
+	UploadThroughput   int
+}
+
+// Network condition presets, same as in Chrome DevTools.
+var (
+	NetworkOffline = NetworkConditions{Offline: true}
+
+	NetworkSlow3G = NetworkConditions{
+		Latency:            2000 * time.Millisecond,
+		DownloadThroughput: 50000, // 400 Kbps
+		UploadThroughput:   50000, // 400 Kbps
+	}
+
+	NetworkFast3G = NetworkConditions{
+		Latency:            562500 * time.Microsecond,
+		DownloadThroughput: 180000, // 1.44 Mbps
+		UploadThroughput:   84375,  // 675 Kbps
+	}
+
+	NetworkFast4G = NetworkConditions{
+		Latency:            165 * time.Millisecond,
+		DownloadThroughput: 1012500, // 8.1 Mbps
+		UploadThroughput:   168750,  // 1.35 Mbps
+	}
+)
+
+// NetworkPreset - returns network conditions preset by its DevTools name ("Offline", "Slow 3G", "Fast 3G", "Fast 4G"),
+// name is case insensitive.
+func NetworkPreset(name string) (NetworkConditions, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "offline":
+		return NetworkOffline, true
+	case "slow 3g":
+		return NetworkSlow3G, true
+	case "fast 3g", "slow 4g":
+		return NetworkFast3G, true
+	case "fast 4g":
+		return NetworkFast4G, true
+	}
+
+	return NetworkConditions{}, false
+}
+
+// enabled - reports whether any network emulation is requested.
+func (n NetworkConditions) enabled() bool {
+	return n.Offline || n.Latency > 0 || n.DownloadThroughput > 0 || n.UploadThroughput > 0
+}
+
+// cpuThrottlingRate - returns CPU slowdown factor, rates below 1 mean no throttling.
+func (c *Config) cpuThrottlingRate() float64 {
+	return max(c.CPUThrottlingRate, 1)
+}
+
+// emulateNetwork - applies network conditions to page, Network domain must be enabled.
+func (c *Config) emulateNetwork(ctx context.Context, client *cdp.Client) error {
+	throughput := func(v int) float64 {
+		if v <= 0 {
+			return -1 // disables throttling
+		}
+
+		return float64(v)
+	}
+
+	return client.Network.EmulateNetworkConditions(ctx, network.NewEmulateNetworkConditionsArgs(
+		c.Network.Offline,
+		float64(c.Network.Latency)/float64(time.Millisecond),
+		throughput(c.Network.DownloadThroughput),
+		throughput(c.Network.UploadThroughput),
+	))
+}
@@ -0,0 +1,136 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mafredri/cdp"
+	"github.com/mafredri/cdp/protocol/emulation"
+	"github.com/mafredri/cdp/protocol/page"
+	"github.com/mafredri/cdp/protocol/runtime"
+)
+
+// Viewport - device metrics used for one of multiple captures of the same page.
+type Viewport struct {
+	Width  int
+	Height int
+
+	DeviceScaleFactor float64
+	Mobile            bool
+}
+
+// ViewportImage - capture of page made at viewport.
+type ViewportImage struct {
+	Viewport Viewport
+
+	Image  []byte
+	Width  float64
+	Height float64
+}
+
+// settleLayoutExpression - resolves after two animation frames, when layout caused by resize is painted.
+const settleLayoutExpression = `new Promise(resolve => requestAnimationFrame(() => requestAnimationFrame(resolve)))`
+
+// captureViewports - re-applies device metrics of every viewport to already loaded page and captures it,
+// captures are padded and scaled to output size like main image.
+func (c *Config) captureViewports(ctx context.Context, client *cdp.Client, log *slog.Logger) ([]ViewportImage, error) {
+	images := make([]ViewportImage, 0, len(c.Viewports))
+
+	for _, vp := range c.Viewports {
+		start := time.Now()
+
+		scale := vp.DeviceScaleFactor
+		if scale <= 0 {
+			scale = 1
+		}
+
+		err := client.Emulation.SetDeviceMetricsOverride(ctx, &emulation.SetDeviceMetricsOverrideArgs{
+			Width:             vp.Width,
+			Height:            vp.Height,
+			DeviceScaleFactor: scale,
+			Mobile:            vp.Mobile,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to set %dx%d viewport: %w", vp.Width, vp.Height, err)
+		}
+
+		err = c.settleLayout(ctx, client)
+		if err != nil {
+			return nil, fmt.Errorf("failed to wait for %dx%d viewport layout: %w", vp.Width, vp.Height, err)
+		}
+
+		width, height := float64(vp.Width), float64(vp.Height)
+
+		if c.FullPage {
+			layout, err := client.Page.GetLayoutMetrics(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get %dx%d viewport layout metrics: %w", vp.Width, vp.Height, err)
+			}
+
+			width, height = layout.CSSContentSize.Width, layout.CSSContentSize.Height
+
+			// page must be as tall as its content, otherwise area below the fold is not painted
+			if height > layout.CSSVisualViewport.ClientHeight {
+				err = client.Emulation.SetDeviceMetricsOverride(ctx, &emulation.SetDeviceMetricsOverrideArgs{
+					Width:             vp.Width,
+					Height:            int(height),
+					DeviceScaleFactor: scale,
+					Mobile:            vp.Mobile,
+				})
+				if err != nil {
+					return nil, fmt.Errorf("failed to set %dx%d viewport full page metrics: %w", vp.Width, vp.Height, err)
+				}
+
+				err = c.settleLayout(ctx, client)
+				if err != nil {
+					return nil, fmt.Errorf("failed to wait for %dx%d viewport layout: %w", vp.Width, vp.Height, err)
+				}
+			}
+		}
+
+		scr, err := client.Page.CaptureScreenshot(ctx, page.NewCaptureScreenshotArgs().
+			SetFormat("png").
+			SetClip(c.clip(width, height)),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to capture %dx%d viewport: %w", vp.Width, vp.Height, err)
+		}
+
+		img, width, height, err := c.finish(scr.Data, width, height)
+		if err != nil {
+			return nil, fmt.Errorf("failed to finish %dx%d viewport: %w", vp.Width, vp.Height, err)
+		}
+
+		log.Debug("viewport captured",
+			slog.Int("viewport_width", vp.Width),
+			slog.Int("viewport_height", vp.Height),
+			slog.Int("bytes", len(img)),
+			slog.Duration("duration", time.Since(start)),
+		)
+
+		images = append(images, ViewportImage{
+			Viewport: vp,
+			Image:    img,
+			Width:    width,
+			Height:   height,
+		})
+	}
+
+	return images, nil
+}
+
+// settleLayout - waits until page has painted layout changes.
+func (c *Config) settleLayout(ctx context.Context, client *cdp.Client) error {
+	reply, err := client.Runtime.Evaluate(ctx, runtime.NewEvaluateArgs(settleLayoutExpression).SetAwaitPromise(true))
+	if err != nil {
+		return err
+	}
+
+	if reply.ExceptionDetails != nil {
+		return reply.ExceptionDetails
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package screenshot
+
+import (
+	"cmp"
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// WatermarkPosition - edge of image watermark banner is drawn at.
+type WatermarkPosition string
+
+// Watermark banner positions.
+const (
+	WatermarkBottom WatermarkPosition = ""
+	WatermarkTop    WatermarkPosition = "top"
+)
+
+const (
+	// defaultWatermarkText - banner text when Watermark.Text is empty.
+	defaultWatermarkText = "{url}  {time}"
+
+	// defaultWatermarkFontSize - banner font size in pixels when Watermark.FontSize is not set.
+	defaultWatermarkFontSize = 14
+)
+
+// watermarkFont - monospace Go font, parsed once.
+var watermarkFont = sync.OnceValues(func() (*opentype.Font, error) {
+	return opentype.Parse(gomono.TTF)
+})
+
+// Watermark - processor that extends image with banner showing final captured URL and UTC capture time,
+// page DOM is not modified. Text that does not fit image width is truncated.
+type Watermark struct {
+	// Text - banner text, "{url}" and "{time}" are replaced by URL after redirects and RFC 3339 UTC capture time,
+	// "{url}  {time}" when empty.
+	Text string
+
+	// FontSize - font size in pixels, 14 when not set.
+	FontSize float64
+
+	Position WatermarkPosition
+
+	// Foreground, Background - text and banner colours, white on black when not set.
+	Foreground color.Color
+	Background color.Color
+}
+
+// Process - implements Processor.
+func (w Watermark) Process(_ context.Context, img []byte, meta Result) ([]byte, error) {
+	src, err := decodeImage(img)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := watermarkFont()
+	if err != nil {
+		return nil, err
+	}
+
+	size := w.FontSize
+	if size <= 0 {
+		size = defaultWatermarkFontSize
+	}
+
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+
+	metrics := face.Metrics()
+	margin := metrics.Height.Ceil() / 3
+	height := metrics.Height.Ceil() + 2*margin
+
+	// canvas is extended like padding, so banner never covers page content
+	out := image.NewRGBA(image.Rect(0, 0, src.Bounds().Dx(), src.Bounds().Dy()+height))
+
+	banner := image.Rect(0, src.Bounds().Dy(), out.Bounds().Dx(), out.Bounds().Dy())
+	content := image.Rect(0, 0, src.Bounds().Dx(), src.Bounds().Dy())
+
+	if w.Position == WatermarkTop {
+		banner = image.Rect(0, 0, out.Bounds().Dx(), height)
+		content = content.Add(image.Pt(0, height))
+	}
+
+	draw.Draw(out, content, src, src.Bounds().Min, draw.Src)
+	draw.Draw(out, banner, image.NewUniform(colorOr(w.Background, color.Black)), image.Point{}, draw.Src)
+
+	d := &font.Drawer{
+		Dst:  out,
+		Src:  image.NewUniform(colorOr(w.Foreground, color.White)),
+		Face: face,
+		Dot:  fixed.P(banner.Min.X+margin, banner.Min.Y+margin+metrics.Ascent.Ceil()),
+	}
+
+	d.DrawString(fitText(face, w.text(meta), fixed.I(banner.Dx()-2*margin)))
+
+	return encodePNG(out, png.DefaultCompression)
+}
+
+// text - returns banner text for capture.
+func (w Watermark) text(meta Result) string {
+	text := w.Text
+	if text == "" {
+		text = defaultWatermarkText
+	}
+
+	capturedAt := meta.CapturedAt
+	if capturedAt.IsZero() {
+		capturedAt = time.Now()
+	}
+
+	return strings.NewReplacer(
+		"{url}", cmp.Or(meta.FinalURL, meta.URL),
+		"{time}", capturedAt.UTC().Format(time.RFC3339),
+	).Replace(text)
+}
+
+// fitText - truncates text with ellipsis so it fits into width.
+func fitText(face font.Face, text string, width fixed.Int26_6) string {
+	if font.MeasureString(face, text) <= width {
+		return text
+	}
+
+	runes := []rune(text)
+
+	for n := len(runes) - 1; n > 0; n-- {
+		s := string(runes[:n]) + "…"
+		if font.MeasureString(face, s) <= width {
+			return s
+		}
+	}
+
+	return ""
+}
+
+// colorOr - returns c or fallback when c is nil.
+func colorOr(c, fallback color.Color) color.Color {
+	if c != nil {
+		return c
+	}
+
+	return fallback
+}